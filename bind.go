@@ -0,0 +1,447 @@
+// MIT License
+
+// Copyright (c) 2019 tanzy2018
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package groute
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/golang/protobuf/proto"
+	"github.com/ugorji/go/codec"
+	"gopkg.in/yaml.v2"
+)
+
+// sourceTags - tags of the sources besides the body,
+// from the lowest precedence to the highest.
+var sourceTags = []string{"query", "cookie", "header", "uri"}
+
+// BindError - the request value can't be decoded into the Param,
+// the ErrHandle receives them in BindErrors apart from the validation error map.
+type BindError struct {
+	// Field - wire path of the field,e.g. items[1].qty,empty if the whole body is malformed.
+	Field string
+	// Source - where the value is read from,
+	// one of json,xml,yaml,protobuf,msgpack,form,query,cookie,header and uri.
+	Source string
+	// Expected - type of the field.
+	Expected string
+	// Value - the offending value.
+	Value string
+	// Err - the decoding error.
+	Err error
+}
+
+func (e *BindError) Error() string {
+	if e.Field == "" {
+		return fmt.Sprintf("malformed %s body: %v", e.Source, e.Err)
+	}
+	return fmt.Sprintf("param '%s' with value '%s' is not a valid %s", e.Field, e.Value, e.Expected)
+}
+
+func (e *BindError) Unwrap() error {
+	return e.Err
+}
+
+// MarshalJSON - the error is encoded with its message.
+func (e *BindError) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Field    string `json:"field,omitempty"`
+		Source   string `json:"source"`
+		Expected string `json:"expected,omitempty"`
+		Value    string `json:"value,omitempty"`
+		Msg      string `json:"msg"`
+	}{e.Field, e.Source, e.Expected, e.Value, e.Error()})
+}
+
+// BindErrors - all the binding errors of the request.
+type BindErrors []*BindError
+
+func (e BindErrors) Error() string {
+	msgs := make([]string, 0, len(e))
+	for _, err := range e {
+		msgs = append(msgs, err.Error())
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// bindRequest - decode all the sources of the request into ptr without validating,
+// so that the router validates ptr once by its own validator.
+// The body is decoded first,then query,cookie,header and uri in order,
+// the later source overrides the field set by the former one.
+// It returns the tag of the body,which names the body fields in the error map,
+// and the BindErrors of all the sources.
+func bindRequest(c *gin.Context, ptr interface{}) (string, error) {
+	var errs BindErrors
+	bodyTag, err := bindBody(c, ptr)
+	if err != nil {
+		errs = append(errs, err.(BindErrors)...)
+	}
+	for _, tag := range sourceTags {
+		if err := mapByTag(ptr, tag, sourceGetter(c, tag)); err != nil {
+			errs = append(errs, err.(BindErrors)...)
+		}
+	}
+	if len(errs) > 0 {
+		return bodyTag, errs
+	}
+	return bodyTag, nil
+}
+
+func sourceGetter(c *gin.Context, tag string) valueGetter {
+	switch tag {
+	case "query":
+		query := c.Request.URL.Query()
+		return func(key string) ([]string, bool) {
+			vs, ok := query[key]
+			return vs, ok
+		}
+	case "cookie":
+		return func(key string) ([]string, bool) {
+			var vs []string
+			for _, cookie := range c.Request.Cookies() {
+				if cookie.Name == key {
+					vs = append(vs, cookie.Value)
+				}
+			}
+			return vs, len(vs) > 0
+		}
+	case "header":
+		return func(key string) ([]string, bool) {
+			vs, ok := c.Request.Header[textproto.CanonicalMIMEHeaderKey(key)]
+			return vs, ok
+		}
+	case "uri":
+		return func(key string) ([]string, bool) {
+			v, ok := c.Params.Get(key)
+			return []string{v}, ok
+		}
+	}
+	return func(string) ([]string, bool) { return nil, false }
+}
+
+// bindBody - decode the body by the Content-Type,
+// GET requests are decoded from the query string by the `form` tag as gin does.
+func bindBody(c *gin.Context, ptr interface{}) (string, error) {
+	req := c.Request
+	if req.Method == http.MethodGet {
+		return "form", bindForm(req, ptr)
+	}
+	contentType := c.ContentType()
+	tag := getTagByContentType(contentType)
+	switch contentType {
+	case gin.MIMEJSON:
+		return tag, decodeBody(req, "json", func(body []byte) error {
+			decoder := json.NewDecoder(bytes.NewReader(body))
+			if binding.EnableDecoderUseNumber {
+				decoder.UseNumber()
+			}
+			err := decoder.Decode(ptr)
+			if e, ok := err.(*json.UnmarshalTypeError); ok && e.Field != "" {
+				return BindErrors{{
+					Field:    jsonFieldPath(reflect.TypeOf(ptr), e.Field),
+					Source:   "json",
+					Expected: e.Type.String(),
+					Value:    jsonValue(body, e),
+					Err:      err,
+				}}
+			}
+			return err
+		})
+	case gin.MIMEXML, gin.MIMEXML2:
+		return tag, decodeBody(req, "xml", func(body []byte) error {
+			return xml.NewDecoder(bytes.NewReader(body)).Decode(ptr)
+		})
+	case gin.MIMEYAML:
+		return tag, decodeBody(req, "yaml", func(body []byte) error {
+			return yaml.Unmarshal(body, ptr)
+		})
+	case binding.MIMEPROTOBUF:
+		return tag, decodeBody(req, "protobuf", func(body []byte) error {
+			msg, ok := ptr.(proto.Message)
+			if !ok {
+				return fmt.Errorf("%T is not a proto.Message", ptr)
+			}
+			return proto.Unmarshal(body, msg)
+		})
+	case binding.MIMEMSGPACK, binding.MIMEMSGPACK2:
+		return tag, decodeBody(req, "msgpack", func(body []byte) error {
+			return codec.NewDecoderBytes(body, new(codec.MsgpackHandle)).Decode(ptr)
+		})
+	}
+	// same as gin,the other Content-Types are bound as form.
+	return "form", bindForm(req, ptr)
+}
+
+// decodeBody - an empty body leaves ptr untouched,
+// the decoding error is returned as the BindErrors of the source.
+func decodeBody(req *http.Request, source string, decode func([]byte) error) error {
+	if req.Body == nil || req.Body == http.NoBody {
+		return nil
+	}
+	body, err := ioutil.ReadAll(req.Body)
+	if err != nil {
+		return BindErrors{{Source: source, Err: err}}
+	}
+	if len(bytes.TrimSpace(body)) == 0 {
+		return nil
+	}
+	if err := decode(body); err != nil && err != io.EOF {
+		if errs, ok := err.(BindErrors); ok {
+			return errs
+		}
+		return BindErrors{{Source: source, Err: err}}
+	}
+	return nil
+}
+
+// jsonFieldPath - convert the field of the json type error to the wire path,
+// e.g. items.1.qty to items[1].qty,the indexes and map keys are told by the type of ptr.
+func jsonFieldPath(t reflect.Type, field string) string {
+	var path string
+	for _, seg := range strings.Split(field, ".") {
+		for t != nil && t.Kind() == reflect.Ptr {
+			t = t.Elem()
+		}
+		if t != nil {
+			switch t.Kind() {
+			case reflect.Slice, reflect.Array, reflect.Map:
+				path += "[" + seg + "]"
+				t = t.Elem()
+				continue
+			}
+		}
+		if path != "" {
+			path += "."
+		}
+		path += seg
+		t = jsonFieldType(t, seg)
+	}
+	return path
+}
+
+// jsonFieldType - type of the struct field named by the json key,nil if not found.
+func jsonFieldType(t reflect.Type, key string) reflect.Type {
+	if t == nil || t.Kind() != reflect.Struct {
+		return nil
+	}
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name := fieldTagName("json", field)
+		if name == "" && field.Anonymous {
+			ft := field.Type
+			for ft.Kind() == reflect.Ptr {
+				ft = ft.Elem()
+			}
+			if ft := jsonFieldType(ft, key); ft != nil {
+				return ft
+			}
+			continue
+		}
+		if name == key || name == "" && strings.EqualFold(field.Name, key) {
+			return field.Type
+		}
+	}
+	return nil
+}
+
+// jsonValue - the offending literal of the json type error,
+// which ends at the offset of the error.
+func jsonValue(body []byte, e *json.UnmarshalTypeError) string {
+	end := int(e.Offset)
+	if end > len(body) {
+		return e.Value
+	}
+	switch {
+	case strings.HasPrefix(e.Value, "number "):
+		return strings.TrimPrefix(e.Value, "number ")
+	case e.Value == "string":
+		for start := end - 2; start >= 0; start-- {
+			if body[start] != '"' {
+				continue
+			}
+			escapes := 0
+			for i := start - 1; i >= 0 && body[i] == '\\'; i-- {
+				escapes++
+			}
+			if escapes%2 == 0 {
+				if v, err := strconv.Unquote(string(body[start:end])); err == nil {
+					return v
+				}
+				break
+			}
+		}
+	case e.Value == "bool":
+		for _, v := range []string{"true", "false"} {
+			if bytes.HasSuffix(body[:end], []byte(v)) {
+				return v
+			}
+		}
+	}
+	return e.Value
+}
+
+// bindForm - bind the query string and the form body by the `form` tag.
+func bindForm(req *http.Request, ptr interface{}) error {
+	if err := req.ParseForm(); err != nil {
+		return BindErrors{{Source: "form", Err: err}}
+	}
+	if err := req.ParseMultipartForm(defaultMemory); err != nil && err != http.ErrNotMultipart {
+		return BindErrors{{Source: "form", Err: err}}
+	}
+	if req.MultipartForm != nil {
+		bindFiles(reflect.ValueOf(ptr), req.MultipartForm)
+	}
+	return mapByTag(ptr, "form", func(key string) ([]string, bool) {
+		vs, ok := req.Form[key]
+		return vs, ok
+	})
+}
+
+// max memory of the multipart form,same as gin.
+const defaultMemory = 32 << 20
+
+var (
+	fileHeaderType      = reflect.TypeOf(multipart.FileHeader{})
+	fileHeaderPtrType   = reflect.TypeOf(&multipart.FileHeader{})
+	fileHeaderSliceType = reflect.TypeOf([]*multipart.FileHeader{})
+)
+
+// bindFiles - set the uploaded files to the `form` tagged fields.
+func bindFiles(value reflect.Value, form *multipart.Form) {
+	for value.Kind() == reflect.Ptr {
+		if value.IsNil() {
+			return
+		}
+		value = value.Elem()
+	}
+	if value.Kind() != reflect.Struct {
+		return
+	}
+	t := value.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field, fv := t.Field(i), value.Field(i)
+		if !fv.CanSet() {
+			continue
+		}
+		key := fieldTagName("form", field)
+		if key == "-" {
+			continue
+		}
+		name := key
+		if name == "" {
+			name = field.Name
+		}
+		files := form.File[name]
+		switch field.Type {
+		case fileHeaderType:
+			if len(files) > 0 {
+				fv.Set(reflect.ValueOf(*files[0]))
+			}
+		case fileHeaderPtrType:
+			if len(files) > 0 {
+				fv.Set(reflect.ValueOf(files[0]))
+			}
+		case fileHeaderSliceType:
+			if len(files) > 0 {
+				fv.Set(reflect.ValueOf(files))
+			}
+		default:
+			if key == "" {
+				bindFiles(fv, form)
+			}
+		}
+	}
+}
+
+// errFieldPath - wire path of the field in the error map,e.g. items[2].sku or address.zip,
+// namespace is the path by the struct field names below t,e.g. Items[2].Sku.
+// Each level is named by the source with the highest precedence which the field is bound from,
+// then the body tag,then the field name; the source keys are flat so a source tagged field starts the path over,
+// the structs flattened by the binding,embedded ones or untagged ones of the form,leave no level.
+// It returns the struct field at the end of the path as well.
+func errFieldPath(t reflect.Type, bodyTag string, namespace string) (string, reflect.StructField, bool) {
+	var (
+		path  string
+		field reflect.StructField
+	)
+	segs := splitNamespace(namespace)
+	for i, seg := range segs {
+		for t.Kind() == reflect.Ptr {
+			t = t.Elem()
+		}
+		if t.Kind() != reflect.Struct {
+			return "", field, false
+		}
+		sf, ok := t.FieldByName(seg.name)
+		if !ok {
+			return "", field, false
+		}
+		field, t = sf, sf.Type
+		for n := 0; n < seg.indexes; n++ {
+			for t.Kind() == reflect.Ptr {
+				t = t.Elem()
+			}
+			switch t.Kind() {
+			case reflect.Slice, reflect.Array, reflect.Map:
+				t = t.Elem()
+			}
+		}
+
+		name, flat := "", false
+		for j := len(sourceTags) - 1; j >= 0 && name == ""; j-- {
+			if n := fieldTagName(sourceTags[j], sf); n != "" && n != "-" {
+				name, flat = n, true
+			}
+		}
+		if name == "" {
+			if n := fieldTagName(bodyTag, sf); n != "" && n != "-" {
+				name = n
+			}
+		}
+		if name == "" {
+			last := i == len(segs)-1
+			if !last && seg.indexes == 0 && (sf.Anonymous || bodyTag == "form") {
+				continue
+			}
+			name = sf.Name
+		}
+		if flat || path == "" {
+			path = name + seg.index
+		} else {
+			path += "." + name + seg.index
+		}
+	}
+	return path, field, path != ""
+}
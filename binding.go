@@ -23,6 +23,7 @@
 package groute
 
 import (
+	"fmt"
 	"reflect"
 	"sync"
 
@@ -37,6 +38,7 @@ import (
 	zhongwen "github.com/go-playground/locales/zh"
 	zhongwenTW "github.com/go-playground/locales/zh_Hant_TW"
 	ut "github.com/go-playground/universal-translator"
+	validatorv8 "gopkg.in/go-playground/validator.v8"
 	"gopkg.in/go-playground/validator.v9"
 	en_trans "gopkg.in/go-playground/validator.v9/translations/en"
 	fr_trans "gopkg.in/go-playground/validator.v9/translations/fr"
@@ -49,14 +51,29 @@ import (
 	zhTW_trans "gopkg.in/go-playground/validator.v9/translations/zh_tw"
 )
 
+// newStructValidator - create the validator of the version,
+// each router owns its validator instead of the global one of gin.
+func newStructValidator(version string, locale string, tagName string) binding.StructValidator {
+	switch version {
+	case "v9":
+		return &defaultValidator{locale: locale, tagName: tagName}
+	case "v10":
+		return &validatorV10{locale: locale, tagName: tagName}
+	}
+	return &validatorV8{tagName: tagName}
+}
+
 type defaultValidator struct {
-	once     sync.Once
-	validate *validator.Validate
+	once        sync.Once
+	validate    *validator.Validate
+	translators map[string]ut.Translator
+	// locale - default locale of the translations.
+	locale string
+	// tagName - tag of the validation rules.
+	tagName string
 }
 
 var _ binding.StructValidator = &defaultValidator{}
-var defaultLocale = "en"
-var translator ut.Translator
 
 // ValidateStruct receives any kind of type, but only performed struct or pointer to struct type.
 func (v *defaultValidator) ValidateStruct(obj interface{}) error {
@@ -86,16 +103,94 @@ func (v *defaultValidator) Engine() interface{} {
 func (v *defaultValidator) lazyinit() {
 	v.once.Do(func() {
 		v.validate = validator.New()
-		if err := v.registerTranslations(
-			defaultLocale,
-			v.validate,
-			v.getTrans(defaultLocale)); err != nil {
-			panic(err)
+		// all the supported translators are loaded,the locale is picked per request.
+		v.translators = make(map[string]ut.Translator, len(supportedLocales))
+		for _, locale := range supportedLocales {
+			trans := getTrans(locale)
+			if err := v.registerTranslations(locale, v.validate, trans); err != nil {
+				panic(err)
+			}
+			v.translators[locale] = trans
 		}
-		v.validate.SetTagName("binding")
+		v.validate.SetTagName(v.tagName)
+		v.registerV8Aliases()
 	})
 }
 
+// registerValidation - register the rule and its translations to every loaded translator.
+func (v *defaultValidator) registerValidation(tag string, fn interface{}, translations map[string]string) error {
+	f, ok := convertFunc(fn, reflect.TypeOf(validator.Func(nil)))
+	if !ok {
+		return fmt.Errorf("the given func [realType:%T] is not a validator.v9 Func", fn)
+	}
+	v.lazyinit()
+	if err := v.validate.RegisterValidation(tag, f.(validator.Func)); err != nil {
+		return err
+	}
+	for locale, trans := range v.translators {
+		text := translationOf(translations, locale, v.locale)
+		if text == "" {
+			continue
+		}
+		if err := v.validate.RegisterTranslation(tag, trans,
+			func(trans ut.Translator) error {
+				return trans.Add(tag, text, true)
+			},
+			func(trans ut.Translator, fe validator.FieldError) string {
+				t, _ := trans.T(tag, fe.Field(), fe.Param())
+				return t
+			}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// registerStructValidation - register the struct level validation of the type.
+func (v *defaultValidator) registerStructValidation(typ interface{}, fn interface{}) error {
+	f, ok := convertFunc(fn, reflect.TypeOf(validator.StructLevelFunc(nil)))
+	if !ok {
+		return fmt.Errorf("the given func [realType:%T] is not a validator.v9 StructLevelFunc", fn)
+	}
+	v.lazyinit()
+	v.validate.RegisterStructValidation(f.(validator.StructLevelFunc), typ)
+	return nil
+}
+
+// v8Aliases - rules only supported by the validator v8 and their replacements,
+// so that the Param structs written for v8 keep working after upgrading.
+var v8Aliases = map[string]string{
+	"exists": "required",
+}
+
+// registerV8Aliases - register the aliases and translate them as their replacements.
+func (v *defaultValidator) registerV8Aliases() {
+	for alias, tag := range v8Aliases {
+		tag := tag
+		v.validate.RegisterAlias(alias, tag)
+		for _, trans := range v.translators {
+			v.validate.RegisterTranslation(alias, trans,
+				func(ut.Translator) error { return nil },
+				func(trans ut.Translator, fe validator.FieldError) string {
+					t, _ := trans.T(tag, fe.Field())
+					return t
+				})
+		}
+	}
+}
+
+// translator - translator of the locale,fall back to the default locale.
+func (v *defaultValidator) translator(locale string) ut.Translator {
+	v.lazyinit()
+	if trans, ok := v.translators[locale]; ok {
+		return trans
+	}
+	if trans, ok := v.translators[v.locale]; ok {
+		return trans
+	}
+	return v.translators["en"]
+}
+
 func (v *defaultValidator) registerTranslations(
 	locale string, validate *validator.Validate, trans ut.Translator) error {
 	switch locale {
@@ -123,7 +218,8 @@ func (v *defaultValidator) registerTranslations(
 	}
 }
 
-func (v *defaultValidator) getTrans(locale string) ut.Translator {
+// getTrans - create the translator of the locale.
+func getTrans(locale string) ut.Translator {
 	var localeInstance locales.Translator
 	switch locale {
 	case "en", "nl":
@@ -154,6 +250,63 @@ func (v *defaultValidator) getTrans(locale string) ut.Translator {
 		locale = "en"
 	}
 	trans, _ := uni.GetTranslator(locale)
-	translator = trans
 	return trans
 }
+
+// validatorV8 - the validator v8,same as the default one of gin.
+type validatorV8 struct {
+	once     sync.Once
+	validate *validatorv8.Validate
+	tagName  string
+}
+
+var _ binding.StructValidator = &validatorV8{}
+
+// ValidateStruct receives any kind of type, but only performed struct or pointer to struct type.
+func (v *validatorV8) ValidateStruct(obj interface{}) error {
+	value := reflect.ValueOf(obj)
+	valueType := value.Kind()
+	if valueType == reflect.Ptr {
+		valueType = value.Elem().Kind()
+	}
+	if valueType == reflect.Struct {
+		v.lazyinit()
+		if err := v.validate.Struct(obj); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// registerValidation - register the rule,v8 can't translate so translations are ignored.
+func (v *validatorV8) registerValidation(tag string, fn interface{}, translations map[string]string) error {
+	f, ok := convertFunc(fn, reflect.TypeOf(validatorv8.Func(nil)))
+	if !ok {
+		return fmt.Errorf("the given func [realType:%T] is not a validator.v8 Func", fn)
+	}
+	v.lazyinit()
+	return v.validate.RegisterValidation(tag, f.(validatorv8.Func))
+}
+
+// registerStructValidation - register the struct level validation of the type.
+func (v *validatorV8) registerStructValidation(typ interface{}, fn interface{}) error {
+	f, ok := convertFunc(fn, reflect.TypeOf(validatorv8.StructLevelFunc(nil)))
+	if !ok {
+		return fmt.Errorf("the given func [realType:%T] is not a validator.v8 StructLevelFunc", fn)
+	}
+	v.lazyinit()
+	v.validate.RegisterStructValidation(f.(validatorv8.StructLevelFunc), typ)
+	return nil
+}
+
+// Engine returns the underlying validator engine.
+func (v *validatorV8) Engine() interface{} {
+	v.lazyinit()
+	return v.validate
+}
+
+func (v *validatorV8) lazyinit() {
+	v.once.Do(func() {
+		v.validate = validatorv8.New(&validatorv8.Config{TagName: v.tagName})
+	})
+}
@@ -0,0 +1,189 @@
+// MIT License
+
+// Copyright (c) 2019 tanzy2018
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package groute
+
+import (
+	"fmt"
+	"reflect"
+	"sync"
+
+	"github.com/gin-gonic/gin/binding"
+	ut "github.com/go-playground/universal-translator"
+	validatorv10 "github.com/go-playground/validator/v10"
+	en_trans "github.com/go-playground/validator/v10/translations/en"
+	fr_trans "github.com/go-playground/validator/v10/translations/fr"
+	id_trans "github.com/go-playground/validator/v10/translations/id"
+	ja_trans "github.com/go-playground/validator/v10/translations/ja"
+	nl_trans "github.com/go-playground/validator/v10/translations/nl"
+	ptBR_trans "github.com/go-playground/validator/v10/translations/pt_BR"
+	tr_trans "github.com/go-playground/validator/v10/translations/tr"
+	zh_trans "github.com/go-playground/validator/v10/translations/zh"
+	zhTW_trans "github.com/go-playground/validator/v10/translations/zh_tw"
+)
+
+// validatorV10 - the validator v10 with the same translations as v9.
+type validatorV10 struct {
+	once        sync.Once
+	validate    *validatorv10.Validate
+	translators map[string]ut.Translator
+	// locale - default locale of the translations.
+	locale string
+	// tagName - tag of the validation rules.
+	tagName string
+}
+
+var _ binding.StructValidator = &validatorV10{}
+
+// ValidateStruct receives any kind of type, but only performed struct or pointer to struct type.
+func (v *validatorV10) ValidateStruct(obj interface{}) error {
+	value := reflect.ValueOf(obj)
+	valueType := value.Kind()
+	if valueType == reflect.Ptr {
+		valueType = value.Elem().Kind()
+	}
+	if valueType == reflect.Struct {
+		v.lazyinit()
+		if err := v.validate.Struct(obj); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Engine returns the underlying *validator.Validate of v10. See validator GoDoc for more info -
+// https://pkg.go.dev/github.com/go-playground/validator/v10
+func (v *validatorV10) Engine() interface{} {
+	v.lazyinit()
+	return v.validate
+}
+
+func (v *validatorV10) lazyinit() {
+	v.once.Do(func() {
+		v.validate = validatorv10.New()
+		v.translators = make(map[string]ut.Translator, len(supportedLocales))
+		for _, locale := range supportedLocales {
+			trans := getTrans(locale)
+			if err := v.registerTranslations(locale, v.validate, trans); err != nil {
+				panic(err)
+			}
+			v.translators[locale] = trans
+		}
+		v.validate.SetTagName(v.tagName)
+		v.registerV8Aliases()
+	})
+}
+
+// registerValidation - register the rule and its translations to every loaded translator.
+func (v *validatorV10) registerValidation(tag string, fn interface{}, translations map[string]string) error {
+	f, ok := convertFunc(fn, reflect.TypeOf(validatorv10.Func(nil)))
+	if !ok {
+		return fmt.Errorf("the given func [realType:%T] is not a validator v10 Func", fn)
+	}
+	v.lazyinit()
+	if err := v.validate.RegisterValidation(tag, f.(validatorv10.Func)); err != nil {
+		return err
+	}
+	for locale, trans := range v.translators {
+		text := translationOf(translations, locale, v.locale)
+		if text == "" {
+			continue
+		}
+		if err := v.validate.RegisterTranslation(tag, trans,
+			func(trans ut.Translator) error {
+				return trans.Add(tag, text, true)
+			},
+			func(trans ut.Translator, fe validatorv10.FieldError) string {
+				t, _ := trans.T(tag, fe.Field(), fe.Param())
+				return t
+			}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// registerStructValidation - register the struct level validation of the type.
+func (v *validatorV10) registerStructValidation(typ interface{}, fn interface{}) error {
+	f, ok := convertFunc(fn, reflect.TypeOf(validatorv10.StructLevelFunc(nil)))
+	if !ok {
+		return fmt.Errorf("the given func [realType:%T] is not a validator v10 StructLevelFunc", fn)
+	}
+	v.lazyinit()
+	v.validate.RegisterStructValidation(f.(validatorv10.StructLevelFunc), typ)
+	return nil
+}
+
+// registerV8Aliases - register the aliases and translate them as their replacements.
+func (v *validatorV10) registerV8Aliases() {
+	for alias, tag := range v8Aliases {
+		tag := tag
+		v.validate.RegisterAlias(alias, tag)
+		for _, trans := range v.translators {
+			v.validate.RegisterTranslation(alias, trans,
+				func(ut.Translator) error { return nil },
+				func(trans ut.Translator, fe validatorv10.FieldError) string {
+					t, _ := trans.T(tag, fe.Field())
+					return t
+				})
+		}
+	}
+}
+
+// translator - translator of the locale,fall back to the default locale.
+func (v *validatorV10) translator(locale string) ut.Translator {
+	v.lazyinit()
+	if trans, ok := v.translators[locale]; ok {
+		return trans
+	}
+	if trans, ok := v.translators[v.locale]; ok {
+		return trans
+	}
+	return v.translators["en"]
+}
+
+func (v *validatorV10) registerTranslations(
+	locale string, validate *validatorv10.Validate, trans ut.Translator) error {
+	switch locale {
+	case "en":
+		return en_trans.RegisterDefaultTranslations(validate, trans)
+	case "zh":
+		return zh_trans.RegisterDefaultTranslations(validate, trans)
+	case "zh_tw":
+		return zhTW_trans.RegisterDefaultTranslations(validate, trans)
+	case "fr":
+		return fr_trans.RegisterDefaultTranslations(validate, trans)
+	case "ja":
+		return ja_trans.RegisterDefaultTranslations(validate, trans)
+	case "id":
+		return id_trans.RegisterDefaultTranslations(validate, trans)
+	case "nl":
+		return nl_trans.RegisterDefaultTranslations(validate, trans)
+	case "pt_BR":
+		return ptBR_trans.RegisterDefaultTranslations(validate, trans)
+	case "tr":
+		return tr_trans.RegisterDefaultTranslations(validate, trans)
+	// default :en
+	default:
+		return en_trans.RegisterDefaultTranslations(validate, trans)
+	}
+}
@@ -0,0 +1,192 @@
+// MIT License
+
+// Copyright (c) 2019 tanzy2018
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package groute
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"time"
+)
+
+// Saturation - what the step does when the concurrent steps reach the limit.
+type Saturation int
+
+const (
+	// SaturationWait - wait for a free slot,the default one.
+	SaturationWait Saturation = iota + 1
+	// SaturationReject - fail the request with *SaturatedError,
+	// which the default ErrHandle responds with the code 503,
+	// the request waits for the slots held by its own steps instead.
+	SaturationReject
+	// SaturationInline - run the step in the goroutine of the request instead.
+	SaturationInline
+)
+
+// SaturatedError - the step is rejected as the concurrent steps reach the limit.
+type SaturatedError struct {
+	Step  string
+	Limit int
+}
+
+func (e *SaturatedError) Error() string {
+	return fmt.Sprintf("the step %s is rejected as the concurrent steps reach the limit %d", e.Step, e.Limit)
+}
+
+// StepMetrics - metrics of the concurrent steps of the router under the limits.
+type StepMetrics struct {
+	// InFlight - the steps running concurrently now.
+	InFlight int64
+	// Waiting - the steps waiting for a free slot now.
+	Waiting int64
+	// Waited - total of the steps which have waited.
+	Waited int64
+	// WaitTime - total time of the waits.
+	WaitTime time.Duration
+	// Rejected - total of the rejected steps.
+	Rejected int64
+	// Inlined - total of the steps run inline as saturated.
+	Inlined int64
+}
+
+// stepMetrics - counters of StepMetrics,shared by all the interfaces of the router.
+type stepMetrics struct {
+	inFlight int64
+	waiting  int64
+	waited   int64
+	waitTime int64
+	rejected int64
+	inlined  int64
+}
+
+// WithStepConcurrency - limit the steps running concurrently of all the interfaces,
+// the steps running inline are not counted.
+func WithStepConcurrency(limit int) Option {
+	return func(opts *Options) {
+		opts.stepConcurrency = limit
+	}
+}
+
+// WithStepSaturation - set what the steps of all the interfaces do when saturated,
+// overridden by Interface.StepSaturation; default SaturationWait.
+func WithStepSaturation(saturation Saturation) Option {
+	return func(opts *Options) {
+		opts.stepSaturation = saturation
+	}
+}
+
+// StepMetrics - snapshot of the metrics of the concurrent steps.
+func (r *Router) StepMetrics() StepMetrics {
+	m := r.stepMetrics
+	return StepMetrics{
+		InFlight: atomic.LoadInt64(&m.inFlight),
+		Waiting:  atomic.LoadInt64(&m.waiting),
+		Waited:   atomic.LoadInt64(&m.waited),
+		WaitTime: time.Duration(atomic.LoadInt64(&m.waitTime)),
+		Rejected: atomic.LoadInt64(&m.rejected),
+		Inlined:  atomic.LoadInt64(&m.inlined),
+	}
+}
+
+// semaphore - slots of the concurrent steps,nil for no limit.
+type semaphore chan struct{}
+
+func newSemaphore(limit int) semaphore {
+	if limit <= 0 {
+		return nil
+	}
+	return make(semaphore, limit)
+}
+
+// limiter - limit the concurrent steps of the Interface by its own limit and the one of the router.
+type limiter struct {
+	sems       []semaphore
+	saturation Saturation
+	metrics    *stepMetrics
+}
+
+func newLimiter(metrics *stepMetrics, saturation Saturation, sems ...semaphore) *limiter {
+	l := &limiter{saturation: saturation, metrics: metrics}
+	for _, sem := range sems {
+		if sem != nil {
+			l.sems = append(l.sems, sem)
+		}
+	}
+	return l
+}
+
+// acquire - take a slot of each semaphore for the step,
+// reports false if the step should run inline.
+// The request holding slots waits instead of being rejected,
+// since its own steps release them.
+func (l *limiter) acquire(ctx context.Context, step string, holding bool) (release func(), concurrent bool, err error) {
+	if len(l.sems) == 0 {
+		return func() {}, true, nil
+	}
+	var taken []semaphore
+	release = func() {
+		l.putBack(taken)
+		atomic.AddInt64(&l.metrics.inFlight, -1)
+	}
+	for _, sem := range l.sems {
+		select {
+		case sem <- struct{}{}:
+			taken = append(taken, sem)
+			continue
+		default:
+		}
+		switch {
+		case l.saturation == SaturationReject && !holding:
+			l.putBack(taken)
+			atomic.AddInt64(&l.metrics.rejected, 1)
+			return nil, false, &SaturatedError{Step: step, Limit: cap(sem)}
+		case l.saturation == SaturationInline:
+			l.putBack(taken)
+			atomic.AddInt64(&l.metrics.inlined, 1)
+			return nil, false, nil
+		}
+		atomic.AddInt64(&l.metrics.waiting, 1)
+		atomic.AddInt64(&l.metrics.waited, 1)
+		begin := time.Now()
+		select {
+		case sem <- struct{}{}:
+			taken = append(taken, sem)
+		case <-ctx.Done():
+			l.putBack(taken)
+			err = ctx.Err()
+		}
+		atomic.AddInt64(&l.metrics.waiting, -1)
+		atomic.AddInt64(&l.metrics.waitTime, int64(time.Since(begin)))
+		if err != nil {
+			return nil, false, err
+		}
+	}
+	atomic.AddInt64(&l.metrics.inFlight, 1)
+	return release, true, nil
+}
+
+func (l *limiter) putBack(taken []semaphore) {
+	for _, sem := range taken {
+		<-sem
+	}
+}
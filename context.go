@@ -34,14 +34,85 @@ type Context struct {
 	sync.Mutex
 	// GinContext - reuse the gin Context.
 	GinContext *gin.Context
-	// ClientContext - context used to call the backend service.
+	// ClientContext - context used to call the backend service,
+	// bound to the StepBudget and canceled once any step fails while the steps run.
 	ClientContext context.Context
 	// Param - store the requested body data.
 	Param interface{}
 	// ErrCode - custome http code when return the error hints.
 	ErrCode interface{}
 	// Extra - data read from special middleware will be set here.
+	//
+	// Deprecated: it's not safe for the concurrent steps,use Set and Get instead.
 	Extra map[string]interface{}
 	// ErrHandle - handle error hints when validate failed.
 	ErrHandle ErrHandle
+	// Locale - locale of the validation hints negotiated for this request.
+	Locale string
+	// ErrorCodes - the error codes of the router,nil if not set.
+	ErrorCodes *ErrorCodes
+	// values - the store of Set and Get,empty for each request.
+	values sync.Map
+	// failedSteps - errors of the optional steps.
+	failedSteps   StepErrors
+	failedStepsMu sync.Mutex
+	// shared - the Context of the request if c is the view of a step which may be left behind,
+	// the lock,the values and the failed steps are shared with it.
+	shared *Context
+}
+
+// request - the Context of the request.
+func (c *Context) request() *Context {
+	if c.shared != nil {
+		return c.shared
+	}
+	return c
+}
+
+// Lock - lock the Context of the request,shared by all the steps.
+func (c *Context) Lock() {
+	c.request().Mutex.Lock()
+}
+
+// TryLock - same as Lock but reports false instead of waiting.
+func (c *Context) TryLock() bool {
+	return c.request().Mutex.TryLock()
+}
+
+// Unlock - unlock the Context of the request.
+func (c *Context) Unlock() {
+	c.request().Mutex.Unlock()
+}
+
+// FailedSteps - errors of the optional steps,
+// so that the handle knows which of them are missing or fallen back.
+func (c *Context) FailedSteps() StepErrors {
+	c = c.request()
+	c.failedStepsMu.Lock()
+	defer c.failedStepsMu.Unlock()
+	return append(StepErrors(nil), c.failedSteps...)
+}
+
+func (c *Context) addFailedStep(err *StepError) {
+	c = c.request()
+	c.failedStepsMu.Lock()
+	c.failedSteps = append(c.failedSteps, err)
+	c.failedStepsMu.Unlock()
+}
+
+// Set - store the value of the key for the later steps and the handle,
+// safe for the concurrent steps.
+func Set[T any](c *Context, key string, v T) {
+	c.request().values.Store(key, v)
+}
+
+// Get - the value of the key,reports false if the key is not set or the value is not a T.
+func Get[T any](c *Context, key string) (T, bool) {
+	v, ok := c.request().values.Load(key)
+	if !ok {
+		var zero T
+		return zero, false
+	}
+	t, ok := v.(T)
+	return t, ok
 }
@@ -0,0 +1,120 @@
+// MIT License
+
+// Copyright (c) 2019 tanzy2018
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package groute
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Casing - casing of the path derived from the method name.
+type Casing int
+
+const (
+	// CasingKebab - e.g. GetStudentInfo → GET /student-info.
+	CasingKebab Casing = iota
+	// CasingSnake - e.g. GetStudentInfo → GET /student_info.
+	CasingSnake
+)
+
+// Convention - derive the path and the method of the interfaces added by struct from the method names,
+// e.g. GetInfo → GET /info,PostScore → POST /score,
+// the method is the one of the interface when the name doesn't start with a method,
+// the explicit Path,Method and Methods of the interface override the derived ones.
+type Convention struct {
+	// Casing - casing of the path,default kebab.
+	Casing Casing
+	// StructPrefix - prefix the path with the struct name,e.g. Student.GetInfo → GET /student/info.
+	StructPrefix bool
+}
+
+// WithConvention - derive the path and the method of the interfaces added by struct.
+func WithConvention(convention Convention) Option {
+	return func(opts *Options) {
+		opts.convention = &convention
+	}
+}
+
+// conventionMethods - the method prefixes of the method names.
+var conventionMethods = []string{"Get", "Post", "Put", "Patch", "Delete", "Head", "Options", "Any"}
+
+func (conv *Convention) apply(inter Interface, structName, methodName string) Interface {
+	method, name := splitMethod(methodName)
+	if inter.Method == "" && len(inter.Methods) == 0 {
+		inter.Method = method
+	}
+	if inter.Path != "" {
+		return inter
+	}
+	inter.Path = "/" + conv.join(splitWords(name))
+	if conv.StructPrefix {
+		inter.Path = strings.TrimSuffix("/"+conv.join(splitWords(structName))+inter.Path, "/")
+	}
+	return inter
+}
+
+func (conv *Convention) join(words []string) string {
+	sep := "-"
+	if conv.Casing == CasingSnake {
+		sep = "_"
+	}
+	return strings.ToLower(strings.Join(words, sep))
+}
+
+// splitMethod - split the method name into the HTTP method and the rest,
+// the HTTP method is empty if the name doesn't start with it,e.g. Getaway.
+func splitMethod(name string) (method, rest string) {
+	for _, m := range conventionMethods {
+		if !strings.HasPrefix(name, m) {
+			continue
+		}
+		rest = name[len(m):]
+		if rest == "" || !unicode.IsLower(rune(rest[0])) {
+			return strings.ToUpper(m), rest
+		}
+	}
+	return "", name
+}
+
+// splitWords - split the camel case name into words,
+// the acronyms are kept together,e.g. UserIDList → User,ID,List.
+func splitWords(name string) []string {
+	runes := []rune(name)
+	var words []string
+	start := 0
+	for i := 1; i < len(runes); i++ {
+		if !unicode.IsUpper(runes[i]) {
+			continue
+		}
+		prev := runes[i-1]
+		nextLower := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+		if unicode.IsLower(prev) || unicode.IsDigit(prev) || (unicode.IsUpper(prev) && nextLower) {
+			words = append(words, string(runes[start:i]))
+			start = i
+		}
+	}
+	if start < len(runes) {
+		words = append(words, string(runes[start:]))
+	}
+	return words
+}
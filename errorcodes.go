@@ -0,0 +1,252 @@
+// MIT License
+
+// Copyright (c) 2019 tanzy2018
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package groute
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// ErrorCode - the business code declared once with its HTTP status and messages.
+type ErrorCode struct {
+	Code int `json:"code"`
+	// Status - HTTP status,default 500.
+	Status int `json:"status"`
+	// Message - the default message.
+	Message string `json:"message"`
+	// Translations - the messages by the locale,e.g. {"zh": "学生不存在"}.
+	Translations map[string]string `json:"translations,omitempty"`
+}
+
+// CodeValidation - the default code of the errors,e.g. the validation errors.
+const CodeValidation = 402
+
+// builtinErrorCodes - the codes responded by the router,which can be redeclared.
+var builtinErrorCodes = []ErrorCode{
+	{
+		Code:         CodeValidation,
+		Status:       http.StatusUnprocessableEntity,
+		Message:      "the request params failed to be validated",
+		Translations: map[string]string{"zh": "请求参数校验失败"},
+	},
+}
+
+// defaultErrorCodes - the registry of the router without WithErrorCodes.
+var defaultErrorCodes = MustErrorCodes()
+
+// ErrorCodes - registry of the business codes.
+type ErrorCodes struct {
+	codes map[int]ErrorCode
+	// sorted - the codes in order.
+	sorted []ErrorCode
+}
+
+// NewErrorCodes - create the registry with the builtin codes,e.g. CodeValidation,which the codes can redeclare,
+// reports all the duplicated codes,invalid statuses,empty messages and unsupported locales.
+func NewErrorCodes(codes ...ErrorCode) (*ErrorCodes, error) {
+	r := &ErrorCodes{codes: make(map[int]ErrorCode, len(codes))}
+	var issues []string
+	for _, code := range codes {
+		if code.Status == 0 {
+			code.Status = http.StatusInternalServerError
+		}
+		if _, ok := r.codes[code.Code]; ok {
+			issues = append(issues, fmt.Sprintf("the code %d is duplicated", code.Code))
+			continue
+		}
+		if code.Status < 100 || code.Status > 599 {
+			issues = append(issues, fmt.Sprintf("the status %d of the code %d is invalid", code.Status, code.Code))
+		}
+		if code.Message == "" {
+			issues = append(issues, fmt.Sprintf("the code %d has no message", code.Code))
+		}
+		var unsupported []string
+		for locale := range code.Translations {
+			if !containsString(supportedLocales, locale) {
+				unsupported = append(unsupported, locale)
+			}
+		}
+		sort.Strings(unsupported)
+		for _, locale := range unsupported {
+			issues = append(issues, fmt.Sprintf("the locale %s of the code %d is not supported", locale, code.Code))
+		}
+		r.codes[code.Code] = code
+		r.sorted = append(r.sorted, code)
+	}
+	for _, code := range builtinErrorCodes {
+		if _, ok := r.codes[code.Code]; !ok {
+			r.codes[code.Code] = code
+			r.sorted = append(r.sorted, code)
+		}
+	}
+	if len(issues) > 0 {
+		return nil, fmt.Errorf("invalid error codes: %s", strings.Join(issues, "; "))
+	}
+	sort.Slice(r.sorted, func(i, j int) bool {
+		return r.sorted[i].Code < r.sorted[j].Code
+	})
+	return r, nil
+}
+
+// MustErrorCodes - same as NewErrorCodes but panics on the invalid codes.
+func MustErrorCodes(codes ...ErrorCode) *ErrorCodes {
+	r, err := NewErrorCodes(codes...)
+	if err != nil {
+		panic(err)
+	}
+	return r
+}
+
+// WithErrorCodes - set the registry used by the ErrHandle to complete the status and the message of the codes.
+func WithErrorCodes(codes *ErrorCodes) Option {
+	return func(opts *Options) {
+		opts.errorCodes = codes
+	}
+}
+
+// Lookup - the declared code,the nil registry has the builtin codes.
+func (r *ErrorCodes) Lookup(code int) (ErrorCode, bool) {
+	if r == nil {
+		r = defaultErrorCodes
+	}
+	c, ok := r.codes[code]
+	return c, ok
+}
+
+// Error - create the Error of the code,
+// the message is left empty so that the ErrHandle translates it by the locale of the request.
+func (r *ErrorCodes) Error(code int) *Error {
+	c, ok := r.Lookup(code)
+	if !ok {
+		return &Error{Code: code}
+	}
+	return &Error{Status: c.Status, Code: code}
+}
+
+// Message - message of the code in the locale,fall back to the default message.
+func (r *ErrorCodes) Message(code int, locale string) string {
+	c, ok := r.Lookup(code)
+	if !ok {
+		return ""
+	}
+	if msg, ok := c.Translations[locale]; ok {
+		return msg
+	}
+	return c.Message
+}
+
+// Codes - all the codes in order.
+func (r *ErrorCodes) Codes() []ErrorCode {
+	if r == nil {
+		r = defaultErrorCodes
+	}
+	return append([]ErrorCode(nil), r.sorted...)
+}
+
+// JSON - the catalogue of the codes in json.
+func (r *ErrorCodes) JSON() ([]byte, error) {
+	return json.MarshalIndent(r.Codes(), "", "  ")
+}
+
+// Markdown - the catalogue of the codes as a markdown table,a column for each translated locale.
+func (r *ErrorCodes) Markdown() string {
+	codes := r.Codes()
+	var locales []string
+	for _, c := range codes {
+		for locale := range c.Translations {
+			if !containsString(locales, locale) {
+				locales = append(locales, locale)
+			}
+		}
+	}
+	sort.Strings(locales)
+	var b strings.Builder
+	b.WriteString("| Code | Status | Message |")
+	for _, locale := range locales {
+		b.WriteString(" " + locale + " |")
+	}
+	b.WriteString("\n| --- | --- | --- |")
+	for range locales {
+		b.WriteString(" --- |")
+	}
+	b.WriteString("\n")
+	for _, c := range codes {
+		fmt.Fprintf(&b, "| %d | %d | %s |", c.Code, c.Status, markdownCell(c.Message))
+		for _, locale := range locales {
+			b.WriteString(" " + markdownCell(c.Translations[locale]) + " |")
+		}
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+func markdownCell(s string) string {
+	return strings.NewReplacer("|", "\\|", "\n", " ").Replace(s)
+}
+
+// describeError - status,code and message of the Error,
+// completed by the error codes of the router.
+func (c *Context) describeError(e *Error) (status int, code interface{}, msg string) {
+	status, msg = e.Status, e.Message
+	code = c.ErrCode
+	if e.Code != 0 {
+		code = e.Code
+	}
+	if n, ok := code.(int); ok {
+		if ec, ok := c.ErrorCodes.Lookup(n); ok {
+			if status == 0 {
+				status = ec.Status
+			}
+			if msg == "" {
+				msg = c.ErrorCodes.Message(n, c.Locale)
+			}
+		}
+	}
+	if status == 0 {
+		status = http.StatusInternalServerError
+	}
+	if msg == "" {
+		msg = http.StatusText(status)
+	}
+	if code == nil {
+		code = status
+	}
+	return
+}
+
+// errorCode - the registered code with the message in the locale of the request.
+func (c *Context) errorCode(code interface{}) (ErrorCode, bool) {
+	n, ok := code.(int)
+	if !ok {
+		return ErrorCode{}, false
+	}
+	ec, ok := c.ErrorCodes.Lookup(n)
+	if !ok {
+		return ErrorCode{}, false
+	}
+	ec.Message = c.ErrorCodes.Message(n, c.Locale)
+	return ec, true
+}
@@ -0,0 +1,196 @@
+// MIT License
+
+// Copyright (c) 2019 tanzy2018
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package groute
+
+import (
+	"errors"
+	"net/http"
+)
+
+// Error - the error with the HTTP status and the business code,
+// which the steps and the handles return to the ErrHandle.
+type Error struct {
+	// Status - HTTP status,default 500.
+	Status int
+	// Code - the business code,overrides Context.ErrCode.
+	Code int
+	// Message - the message to the client,default the text of the status.
+	Message string
+	// Fields - the messages of the fields.
+	Fields map[string]string
+	// Cause - the underlying error,not exposed to the client.
+	Cause error
+	// Details - the extra data to the client.
+	Details interface{}
+}
+
+// NewError - create the Error of the status.
+func NewError(status int, message string) *Error {
+	return &Error{Status: status, Message: message}
+}
+
+// BadRequest - the Error of 400.
+func BadRequest(message string) *Error {
+	return NewError(http.StatusBadRequest, message)
+}
+
+// Unauthorized - the Error of 401.
+func Unauthorized(message string) *Error {
+	return NewError(http.StatusUnauthorized, message)
+}
+
+// Forbidden - the Error of 403.
+func Forbidden(message string) *Error {
+	return NewError(http.StatusForbidden, message)
+}
+
+// NotFound - the Error of 404.
+func NotFound(message string) *Error {
+	return NewError(http.StatusNotFound, message)
+}
+
+// Conflict - the Error of 409.
+func Conflict(message string) *Error {
+	return NewError(http.StatusConflict, message)
+}
+
+// TooManyRequests - the Error of 429.
+func TooManyRequests(message string) *Error {
+	return NewError(http.StatusTooManyRequests, message)
+}
+
+// InternalError - the Error of 500.
+func InternalError(message string) *Error {
+	return NewError(http.StatusInternalServerError, message)
+}
+
+// WithCode - set the business code.
+func (e *Error) WithCode(code int) *Error {
+	e.Code = code
+	return e
+}
+
+// WithFields - set the messages of the fields.
+func (e *Error) WithFields(fields map[string]string) *Error {
+	e.Fields = fields
+	return e
+}
+
+// WithCause - set the underlying error.
+func (e *Error) WithCause(cause error) *Error {
+	e.Cause = cause
+	return e
+}
+
+// WithDetails - set the extra data.
+func (e *Error) WithDetails(details interface{}) *Error {
+	e.Details = details
+	return e
+}
+
+// HTTPStatus - the status,default 500.
+func (e *Error) HTTPStatus() int {
+	if e.Status == 0 {
+		return http.StatusInternalServerError
+	}
+	return e.Status
+}
+
+// Msg - the message,default the text of the status.
+func (e *Error) Msg() string {
+	if e.Message == "" {
+		return http.StatusText(e.HTTPStatus())
+	}
+	return e.Message
+}
+
+func (e *Error) Error() string {
+	if e.Cause != nil {
+		return e.Msg() + ": " + e.Cause.Error()
+	}
+	return e.Msg()
+}
+
+func (e *Error) Unwrap() error {
+	return e.Cause
+}
+
+// asError - the Error in the chain of err.
+func asError(err interface{}) (*Error, bool) {
+	e, ok := err.(error)
+	if !ok {
+		return nil, false
+	}
+	var ge *Error
+	if errors.As(e, &ge) {
+		return ge, true
+	}
+	return nil, false
+}
+
+// errorStatus - HTTP status of the errors found by errors.As,
+// the errors of several steps respond the status they agree on,504 if any of them times out,otherwise 502.
+func errorStatus(err error) (int, bool) {
+	statuses := errorStatuses(err)
+	if len(statuses) == 0 {
+		return 0, false
+	}
+	for _, status := range statuses[1:] {
+		if status == statuses[0] {
+			continue
+		}
+		for _, status := range statuses {
+			if status == http.StatusGatewayTimeout {
+				return status, true
+			}
+		}
+		return http.StatusBadGateway, true
+	}
+	return statuses[0], true
+}
+
+// errorStatuses - statuses of all the known errors in the tree of err.
+func errorStatuses(err error) []int {
+	switch e := err.(type) {
+	case *Error:
+		return []int{e.HTTPStatus()}
+	case BindErrors:
+		return []int{http.StatusBadRequest}
+	case *SaturatedError:
+		return []int{http.StatusServiceUnavailable}
+	case *TimeoutError:
+		return []int{http.StatusGatewayTimeout}
+	case *PanicError:
+		return []int{http.StatusInternalServerError}
+	case interface{ Unwrap() []error }:
+		var statuses []int
+		for _, err := range e.Unwrap() {
+			statuses = append(statuses, errorStatuses(err)...)
+		}
+		return statuses
+	}
+	if err = errors.Unwrap(err); err != nil {
+		return errorStatuses(err)
+	}
+	return nil
+}
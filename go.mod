@@ -1,14 +1,32 @@
 module github.com/tanzy2018/groute
 
-go 1.13
+go 1.18
 
 require (
 	github.com/gin-gonic/gin v1.4.0
 	github.com/go-playground/locales v0.13.0
-	github.com/go-playground/universal-translator v0.16.0
-	github.com/leodido/go-urn v1.2.0 // indirect
+	github.com/go-playground/universal-translator v0.17.0
+	github.com/go-playground/validator/v10 v10.4.1
+	github.com/golang/protobuf v1.3.1
 	github.com/levigross/grequests v0.0.0-20190908174114-253788527a1a
 	github.com/stretchr/testify v1.4.0
+	github.com/ugorji/go v1.1.4
 	gopkg.in/go-playground/validator.v8 v8.18.2
 	gopkg.in/go-playground/validator.v9 v9.30.0
+	gopkg.in/yaml.v2 v2.2.2
+)
+
+require (
+	github.com/davecgh/go-spew v1.1.0 // indirect
+	github.com/gin-contrib/sse v0.0.0-20190301062529-5545eab6dad3 // indirect
+	github.com/google/go-querystring v1.0.0 // indirect
+	github.com/json-iterator/go v1.1.6 // indirect
+	github.com/leodido/go-urn v1.2.0 // indirect
+	github.com/mattn/go-isatty v0.0.7 // indirect
+	github.com/modern-go/concurrent v0.0.0-20180306012644-bacd9c7ef1dd // indirect
+	github.com/modern-go/reflect2 v1.0.1 // indirect
+	github.com/pmezard/go-difflib v1.0.0 // indirect
+	golang.org/x/crypto v0.0.0-20200622213623-75b288015ac9 // indirect
+	golang.org/x/net v0.0.0-20190503192946-f4e77d36d62c // indirect
+	golang.org/x/sys v0.0.0-20190412213103-97732733099d // indirect
 )
@@ -22,6 +22,11 @@
 
 package groute
 
+import (
+	"reflect"
+	"time"
+)
+
 // HandleFunc - handle function.
 type HandleFunc func(*Context)
 
@@ -39,16 +44,38 @@ type Interface struct {
 	SyncHandleFunc ErrHandleFuncChain
 	// AsyncHandleFunc - the special middleware to handle the request param in asynchronous way.
 	AsyncHandleFunc ErrHandleFuncChain
+	// Steps - the named middleware with dependencies,each runs as soon as the steps it's after finish,
+	// the AsyncHandleFunc and SyncHandleFunc run as the steps named async-<index> and sync-<index>.
+	Steps []Step
+	// StepTimeout - default timeout of each step,overrides the one of the router.
+	StepTimeout time.Duration
+	// StepBudget - budget of all the steps of a request,overrides the one of the router.
+	StepBudget time.Duration
+	// StepFailure - how the steps end once any of them fails,overrides the one of the router.
+	StepFailure StepFailure
+	// StepConcurrency - limit of the steps of the Interface running concurrently,
+	// besides the one of the router.
+	StepConcurrency int
+	// StepSaturation - what the steps do when saturated,overrides the one of the router.
+	StepSaturation Saturation
 	// Path - starts with "/".
 	Path string
-	// Method - one of `POST,GET,DELETE,PUT,HEAD,PATCH`,case insensitive.
+	// Method - one of `GET,POST,PUT,PATCH,HEAD,OPTIONS,DELETE,CONNECT,TRACE`
+	// or `ANY` for all of them,case insensitive,default POST.
 	Method string
+	// Methods - more methods served by the interface besides Method.
+	Methods []string
 	// Param - requrest params
 	Param interface{}
 	// Handle function that handles the business logic .
 	Handle HandleFunc
+	// ResultHandle - handles the business logic instead of Handle,
+	// the returned response and error are written by the ResponseEncoder of the router.
+	ResultHandle ResultHandleFunc
 	// ErrHandle
 	ErrHandle ErrHandle
+	// response - type of the response,only known for the typed interface.
+	response reflect.Type
 }
 
 // NewInterface - create a new Interface instance.
@@ -0,0 +1,142 @@
+// MIT License
+
+// Copyright (c) 2019 tanzy2018
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package groute
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// supportedLocales - locales of the validator translations.
+var supportedLocales = []string{"en", "fr", "id", "ja", "nl", "pt_BR", "tr", "zh", "zh_tw"}
+
+// WithLocaleLookup - set where the locale of the validation hints is read from,
+// in the form of "<source>:<name>" joined by ",",the source is one of `header,query,cookie`,
+// e.g. "query:lang,cookie:lang,header:Accept-Language".
+// The first supported locale wins,otherwise the locale of the validator is used;
+// default "header:Accept-Language".
+func WithLocaleLookup(lookup string) Option {
+	return func(opts *Options) {
+		opts.localeLookup = nil
+		for _, l := range strings.Split(lookup, ",") {
+			if l = strings.TrimSpace(l); l != "" {
+				opts.localeLookup = append(opts.localeLookup, l)
+			}
+		}
+	}
+}
+
+// resolveLocale - negotiate the locale of the request,
+// returns "" if none of the lookups gives a supported locale.
+func resolveLocale(c *gin.Context, lookups []string) string {
+	for _, lookup := range lookups {
+		parts := strings.SplitN(lookup, ":", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		var values []string
+		switch source, name := parts[0], parts[1]; source {
+		case "header":
+			if strings.EqualFold(name, "Accept-Language") {
+				values = parseAcceptLanguage(c.GetHeader(name))
+			} else {
+				values = []string{c.GetHeader(name)}
+			}
+		case "query":
+			values = []string{c.Query(name)}
+		case "cookie":
+			if v, err := c.Cookie(name); err == nil {
+				values = []string{v}
+			}
+		}
+		for _, v := range values {
+			if locale := normalizeLocale(v); locale != "" {
+				return locale
+			}
+		}
+	}
+	return ""
+}
+
+// parseAcceptLanguage - languages of the Accept-Language header ordered by the quality.
+func parseAcceptLanguage(header string) []string {
+	type lang struct {
+		tag string
+		q   float64
+	}
+	var langs []lang
+	for _, part := range strings.Split(header, ",") {
+		fields := strings.Split(strings.TrimSpace(part), ";")
+		if fields[0] == "" || fields[0] == "*" {
+			continue
+		}
+		l := lang{tag: fields[0], q: 1}
+		for _, f := range fields[1:] {
+			if f = strings.TrimSpace(f); strings.HasPrefix(f, "q=") {
+				if q, err := strconv.ParseFloat(f[2:], 64); err == nil {
+					l.q = q
+				}
+			}
+		}
+		if l.q > 0 {
+			langs = append(langs, l)
+		}
+	}
+	sort.SliceStable(langs, func(i, j int) bool {
+		return langs[i].q > langs[j].q
+	})
+	tags := make([]string, 0, len(langs))
+	for _, l := range langs {
+		tags = append(tags, l.tag)
+	}
+	return tags
+}
+
+// normalizeLocale - map the language tag to the supported locale,
+// e.g. "zh-CN" to "zh","zh-Hant-TW" to "zh_tw","pt-BR" to "pt_BR".
+func normalizeLocale(tag string) string {
+	tag = strings.ToLower(strings.Replace(strings.TrimSpace(tag), "-", "_", -1))
+	if tag == "" {
+		return ""
+	}
+	parts := strings.Split(tag, "_")
+	switch parts[0] {
+	case "zh":
+		for _, p := range parts[1:] {
+			switch p {
+			case "tw", "hk", "mo", "hant":
+				return "zh_tw"
+			}
+		}
+		return "zh"
+	case "pt":
+		return "pt_BR"
+	}
+	if containsString(supportedLocales, parts[0]) {
+		return parts[0]
+	}
+	return ""
+}
@@ -0,0 +1,310 @@
+// MIT License
+
+// Copyright (c) 2019 tanzy2018
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package groute
+
+import (
+	"encoding"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// valueGetter - get the values of the key from one source of the request.
+type valueGetter func(key string) ([]string, bool)
+
+// mapByTag - set the fields of the struct pointed by ptr from the source,
+// the key is read from the tag and the fields without the tag are skipped,
+// except the form,which keys them by the field name same as gin,
+// nested and embedded structs are walked through.
+func mapByTag(ptr interface{}, tag string, get valueGetter) error {
+	value := reflect.ValueOf(ptr)
+	if value.Kind() != reflect.Ptr || value.IsNil() {
+		return nil
+	}
+	if value.Elem().Kind() != reflect.Struct {
+		return nil
+	}
+	if !hasTaggedField(value.Elem().Type(), tag) {
+		return nil
+	}
+	var errs BindErrors
+	mapStruct(value.Elem(), tag, get, make(map[reflect.Type]bool), &errs)
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// taggedFields - cache of hasTaggedField.
+var taggedFields sync.Map
+
+type taggedKey struct {
+	t   reflect.Type
+	tag string
+}
+
+// hasTaggedField - reports whether the struct or any nested struct has the field tagged for the source,
+// the structs without it are skipped by mapStruct.
+func hasTaggedField(t reflect.Type, tag string) bool {
+	key := taggedKey{t, tag}
+	if v, ok := taggedFields.Load(key); ok {
+		return v.(bool)
+	}
+	tagged := walkTaggedField(t, tag, make(map[reflect.Type]bool))
+	taggedFields.Store(key, tagged)
+	return tagged
+}
+
+func walkTaggedField(t reflect.Type, tag string, visited map[reflect.Type]bool) bool {
+	if visited[t] {
+		return false
+	}
+	visited[t] = true
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.PkgPath != "" {
+			continue
+		}
+		key, _, _ := fieldKey(tag, field)
+		if key == "-" {
+			continue
+		}
+		if key != "" {
+			return true
+		}
+		ft, ok := nestedStruct(field.Type)
+		if !ok && tag == "form" || ok && walkTaggedField(ft, tag, visited) {
+			return true
+		}
+	}
+	return false
+}
+
+// fieldKey - the key of the field in the source and the value of the `default=` option,
+// e.g. `form:"page,default=1"`.
+func fieldKey(tag string, field reflect.StructField) (key string, def string, hasDef bool) {
+	opts := strings.Split(field.Tag.Get(tag), ",")
+	for _, opt := range opts[1:] {
+		if strings.HasPrefix(opt, "default=") {
+			def, hasDef = strings.TrimPrefix(opt, "default="), true
+		}
+	}
+	return opts[0], def, hasDef
+}
+
+// nestedStruct - the struct walked through by mapStruct,time.Time is a value.
+func nestedStruct(t reflect.Type) (reflect.Type, bool) {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t, t.Kind() == reflect.Struct && t != reflect.TypeOf(time.Time{})
+}
+
+// mapStruct - reports whether any field is set,
+// walking holds the structs being walked so that the recursive types stop.
+func mapStruct(value reflect.Value, tag string, get valueGetter, walking map[reflect.Type]bool, errs *BindErrors) (isSet bool) {
+	t := value.Type()
+	walking[t] = true
+	defer delete(walking, t)
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		fv := value.Field(i)
+		if !fv.CanSet() {
+			continue
+		}
+		key, def, hasDef := fieldKey(tag, field)
+		if key == "-" {
+			continue
+		}
+		ft, nested := nestedStruct(field.Type)
+		if key == "" && !nested && tag == "form" {
+			// same as gin.
+			key = field.Name
+		}
+		if key == "" {
+			if !nested || walking[ft] || !hasTaggedField(ft, tag) {
+				continue
+			}
+			// the nil pointer is only allocated when any of its fields is set.
+			if fv.Kind() == reflect.Ptr && fv.IsNil() {
+				ptr := reflect.New(ft)
+				if mapStruct(ptr.Elem(), tag, get, walking, errs) {
+					fv.Set(ptr)
+					isSet = true
+				}
+				continue
+			}
+			for fv.Kind() == reflect.Ptr {
+				fv = fv.Elem()
+			}
+			isSet = mapStruct(fv, tag, get, walking, errs) || isSet
+			continue
+		}
+		vs, ok := get(key)
+		if !ok && !hasDef {
+			continue
+		}
+		if !ok {
+			vs = []string{def}
+		}
+		isSet = true
+		if err := setValues(fv, field, vs); err != nil {
+			var value string
+			if len(vs) > 0 {
+				value = vs[0]
+			}
+			*errs = append(*errs, &BindError{
+				Field:    key,
+				Source:   tag,
+				Expected: field.Type.String(),
+				Value:    value,
+				Err:      err,
+			})
+		}
+	}
+	return
+}
+
+func setValues(value reflect.Value, field reflect.StructField, vs []string) error {
+	switch value.Kind() {
+	case reflect.Slice:
+		if value.Type().Elem().Kind() == reflect.Uint8 {
+			break
+		}
+		slice := reflect.MakeSlice(value.Type(), len(vs), len(vs))
+		for i, v := range vs {
+			if err := setValue(slice.Index(i), field, v); err != nil {
+				return err
+			}
+		}
+		value.Set(slice)
+		return nil
+	case reflect.Array:
+		if len(vs) != value.Len() {
+			return fmt.Errorf("%q is not valid value for %s", vs, value.Type())
+		}
+		for i, v := range vs {
+			if err := setValue(value.Index(i), field, v); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	var v string
+	if len(vs) > 0 {
+		v = vs[0]
+	}
+	return setValue(value, field, v)
+}
+
+func setValue(value reflect.Value, field reflect.StructField, v string) error {
+	if value.Kind() == reflect.Ptr {
+		ptr := reflect.New(value.Type().Elem())
+		if err := setValue(ptr.Elem(), field, v); err != nil {
+			return err
+		}
+		value.Set(ptr)
+		return nil
+	}
+	if u, ok := value.Addr().Interface().(encoding.TextUnmarshaler); ok && value.Type() != reflect.TypeOf(time.Time{}) {
+		return u.UnmarshalText([]byte(v))
+	}
+	switch value.Interface().(type) {
+	case time.Duration:
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return err
+		}
+		value.SetInt(int64(d))
+		return nil
+	case time.Time:
+		return setTime(value, field, v)
+	}
+	switch value.Kind() {
+	case reflect.String:
+		value.SetString(v)
+	case reflect.Bool:
+		if v == "" {
+			v = "false"
+		}
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return err
+		}
+		value.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if v == "" {
+			v = "0"
+		}
+		n, err := strconv.ParseInt(v, 10, value.Type().Bits())
+		if err != nil {
+			return err
+		}
+		value.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if v == "" {
+			v = "0"
+		}
+		n, err := strconv.ParseUint(v, 10, value.Type().Bits())
+		if err != nil {
+			return err
+		}
+		value.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		if v == "" {
+			v = "0"
+		}
+		f, err := strconv.ParseFloat(v, value.Type().Bits())
+		if err != nil {
+			return err
+		}
+		value.SetFloat(f)
+	case reflect.Slice:
+		// []byte
+		value.SetBytes([]byte(v))
+	default:
+		return fmt.Errorf("unsupported type %s", value.Type())
+	}
+	return nil
+}
+
+// setTime - parse the time by the `time_format` tag,default RFC3339.
+func setTime(value reflect.Value, field reflect.StructField, v string) error {
+	if v == "" {
+		value.Set(reflect.ValueOf(time.Time{}))
+		return nil
+	}
+	layout := field.Tag.Get("time_format")
+	if layout == "" {
+		layout = time.RFC3339
+	}
+	t, err := time.Parse(layout, v)
+	if err != nil {
+		return err
+	}
+	value.Set(reflect.ValueOf(t))
+	return nil
+}
@@ -0,0 +1,485 @@
+// MIT License
+
+// Copyright (c) 2019 tanzy2018
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package groute
+
+import (
+	"fmt"
+	"net/http"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// OpenAPIVersion - version of the generated OpenAPI document.
+const OpenAPIVersion = "3.1.0"
+
+// OpenAPI - the OpenAPI document generated from the registered interfaces.
+type OpenAPI struct {
+	OpenAPI string                     `json:"openapi"`
+	Info    OpenAPIInfo                `json:"info"`
+	Paths   map[string]OpenAPIPathItem `json:"paths"`
+}
+
+// OpenAPIInfo - metadata of the api.
+type OpenAPIInfo struct {
+	Title       string `json:"title"`
+	Version     string `json:"version"`
+	Description string `json:"description,omitempty"`
+}
+
+// OpenAPIPathItem - operations of one path keyed by the lower case http method.
+type OpenAPIPathItem map[string]*OpenAPIOperation
+
+// OpenAPIOperation - describes a single api operation.
+type OpenAPIOperation struct {
+	Parameters  []*OpenAPIParameter         `json:"parameters,omitempty"`
+	RequestBody *OpenAPIRequestBody         `json:"requestBody,omitempty"`
+	Responses   map[string]*OpenAPIResponse `json:"responses"`
+}
+
+// OpenAPIParameter - describes a path or query parameter.
+type OpenAPIParameter struct {
+	Name        string  `json:"name"`
+	In          string  `json:"in"`
+	Description string  `json:"description,omitempty"`
+	Required    bool    `json:"required,omitempty"`
+	Schema      *Schema `json:"schema,omitempty"`
+}
+
+// OpenAPIRequestBody - describes the request body keyed by the media type.
+type OpenAPIRequestBody struct {
+	Required bool                         `json:"required,omitempty"`
+	Content  map[string]*OpenAPIMediaType `json:"content"`
+}
+
+// OpenAPIMediaType - schema of one media type.
+type OpenAPIMediaType struct {
+	Schema *Schema `json:"schema,omitempty"`
+}
+
+// OpenAPIResponse - describes a response.
+type OpenAPIResponse struct {
+	Description string                       `json:"description"`
+	Content     map[string]*OpenAPIMediaType `json:"content,omitempty"`
+}
+
+// Schema - the JSON Schema subset used by the OpenAPI document.
+type Schema struct {
+	Type                 string             `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Description          string             `json:"description,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+	Enum                 []interface{}      `json:"enum,omitempty"`
+	Minimum              *float64           `json:"minimum,omitempty"`
+	Maximum              *float64           `json:"maximum,omitempty"`
+	ExclusiveMinimum     *float64           `json:"exclusiveMinimum,omitempty"`
+	ExclusiveMaximum     *float64           `json:"exclusiveMaximum,omitempty"`
+	MinLength            *int               `json:"minLength,omitempty"`
+	MaxLength            *int               `json:"maxLength,omitempty"`
+	MinItems             *int               `json:"minItems,omitempty"`
+	MaxItems             *int               `json:"maxItems,omitempty"`
+}
+
+// WithOpenAPIInfo - set the info of the generated OpenAPI document.
+func WithOpenAPIInfo(info OpenAPIInfo) Option {
+	return func(opts *Options) {
+		opts.openAPIInfo = info
+	}
+}
+
+// WithOpenAPIPath - serve the OpenAPI document on the path,e.g. "/openapi.json".
+func WithOpenAPIPath(path string) Option {
+	return func(opts *Options) {
+		opts.openAPIPath = path
+	}
+}
+
+// OpenAPI - build the OpenAPI document of all the interfaces added to the router.
+func (r *Router) OpenAPI() *OpenAPI {
+	doc := &OpenAPI{
+		OpenAPI: OpenAPIVersion,
+		Info:    r.openAPIInfo,
+		Paths:   make(map[string]OpenAPIPathItem, len(r.routes)),
+	}
+	if doc.Info.Title == "" {
+		doc.Info.Title = "groute"
+	}
+	if doc.Info.Version == "" {
+		doc.Info.Version = "1.0.0"
+	}
+	for _, rt := range r.routes {
+		if rt.method == http.MethodConnect {
+			// not an operation of OpenAPI.
+			continue
+		}
+		p := openAPIPath(rt.path)
+		if doc.Paths[p] == nil {
+			doc.Paths[p] = make(OpenAPIPathItem)
+		}
+		doc.Paths[p][strings.ToLower(rt.method)] = r.openAPIOperation(rt)
+	}
+	return doc
+}
+
+// serve the OpenAPI document,which is built on every request
+// so that interfaces added later are included.
+func (r *Router) serveOpenAPI(path string) error {
+	err := r.handle(http.MethodGet, path, func(c *gin.Context) {
+		c.JSON(http.StatusOK, r.OpenAPI())
+	})
+	if err != nil {
+		return fmt.Errorf("the OpenAPI document [GET %s]: %w", path, err)
+	}
+	return nil
+}
+
+func (r *Router) openAPIOperation(rt route) *OpenAPIOperation {
+	op := &OpenAPIOperation{
+		Responses: map[string]*OpenAPIResponse{
+			"200": &OpenAPIResponse{Description: http.StatusText(http.StatusOK)},
+		},
+	}
+	var uri *Schema
+	if rt.inter.Param != nil {
+		uri = r.schemaOf(reflect.TypeOf(rt.inter.Param), "uri", nil)
+	}
+	for _, name := range pathParams(rt.path) {
+		param := &OpenAPIParameter{
+			Name:     name,
+			In:       "path",
+			Required: true,
+			Schema:   &Schema{Type: "string"},
+		}
+		// path params bound to the `uri` tagged fields.
+		if uri != nil && uri.Properties[name] != nil {
+			param.Schema = uri.Properties[name]
+			param.Description = param.Schema.Description
+		}
+		op.Parameters = append(op.Parameters, param)
+	}
+	if rt.inter.response != nil {
+		op.Responses["200"].Content = map[string]*OpenAPIMediaType{
+			gin.MIMEJSON: &OpenAPIMediaType{Schema: r.schemaOf(rt.inter.response, "json", nil)},
+		}
+	}
+	if rt.inter.Param == nil {
+		return op
+	}
+
+	pType := reflect.TypeOf(rt.inter.Param)
+	for _, in := range []string{"query", "header", "cookie"} {
+		op.addParameters(in, r.schemaOf(pType, in, nil))
+	}
+	switch rt.method {
+	case http.MethodGet, http.MethodHead:
+		// the `form` tagged params are bound from the query string.
+		op.addParameters("query", r.schemaOf(pType, "form", nil))
+	default:
+		jsonSchema := r.schemaOf(pType, "json", nil)
+		op.RequestBody = &OpenAPIRequestBody{
+			Required: len(jsonSchema.Required) > 0,
+			Content: map[string]*OpenAPIMediaType{
+				gin.MIMEJSON:     &OpenAPIMediaType{Schema: jsonSchema},
+				gin.MIMEPOSTForm: &OpenAPIMediaType{Schema: r.schemaOf(pType, "form", nil)},
+			},
+		}
+	}
+	return op
+}
+
+// addParameters - add the properties of the schema as parameters located in the given place.
+func (op *OpenAPIOperation) addParameters(in string, s *Schema) {
+	for _, name := range sortedKeys(s.Properties) {
+		if op.hasParameter(in, name) {
+			continue
+		}
+		op.Parameters = append(op.Parameters, &OpenAPIParameter{
+			Name:        name,
+			In:          in,
+			Description: s.Properties[name].Description,
+			Required:    containsString(s.Required, name),
+			Schema:      s.Properties[name],
+		})
+	}
+}
+
+func (op *OpenAPIOperation) hasParameter(in string, name string) bool {
+	for _, p := range op.Parameters {
+		if p.In == in && p.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// schemaOf - build the schema of the type,field names are read from the tag.
+func (r *Router) schemaOf(t reflect.Type, tagType string, seen map[reflect.Type]bool) *Schema {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t == reflect.TypeOf(time.Time{}) {
+		return &Schema{Type: "string", Format: "date-time"}
+	}
+	switch t.Kind() {
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32:
+		return &Schema{Type: "integer", Format: "int32"}
+	case reflect.Int64:
+		return &Schema{Type: "integer", Format: "int64"}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		min := float64(0)
+		return &Schema{Type: "integer", Minimum: &min}
+	case reflect.Float32:
+		return &Schema{Type: "number", Format: "float"}
+	case reflect.Float64:
+		return &Schema{Type: "number", Format: "double"}
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return &Schema{Type: "string", Format: "byte"}
+		}
+		return &Schema{Type: "array", Items: r.schemaOf(t.Elem(), tagType, seen)}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: r.schemaOf(t.Elem(), tagType, seen)}
+	case reflect.Struct:
+		// recursive types are described only once.
+		if seen[t] {
+			return &Schema{Type: "object"}
+		}
+		if seen == nil {
+			seen = make(map[reflect.Type]bool)
+		}
+		seen[t] = true
+		defer delete(seen, t)
+		s := &Schema{Type: "object", Properties: make(map[string]*Schema)}
+		r.addProperties(s, t, tagType, seen)
+		return s
+	}
+	return &Schema{}
+}
+
+func (r *Router) addProperties(s *Schema, t reflect.Type, tagType string, seen map[reflect.Type]bool) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name := fieldTagName(tagType, field)
+		if name == "-" || (field.PkgPath != "" && !field.Anonymous) {
+			continue
+		}
+		ft := field.Type
+		for ft.Kind() == reflect.Ptr {
+			ft = ft.Elem()
+		}
+		// fields of the embedded struct are promoted.
+		if field.Anonymous && name == "" && ft.Kind() == reflect.Struct {
+			r.addProperties(s, ft, tagType, seen)
+			continue
+		}
+		if field.PkgPath != "" {
+			continue
+		}
+		if name == "" {
+			// fields bound from the other sources are left out.
+			if isSourceTag(tagType) || hasSourceTag(field) {
+				continue
+			}
+			name = field.Name
+		}
+		prop := r.schemaOf(field.Type, tagType, seen)
+		if r.applyRules(prop, field) {
+			s.Required = append(s.Required, name)
+		}
+		s.Properties[name] = prop
+	}
+}
+
+// applyRules - translate the validator rules of the field into the schema,
+// reports whether the field is required.
+func (r *Router) applyRules(s *Schema, field reflect.StructField) (required bool) {
+	var descs []string
+	target := s
+	for _, rule := range strings.Split(field.Tag.Get(r.validatorTag), ",") {
+		// alternative rules can't be described by the schema.
+		if rule == "" || strings.Contains(rule, "|") {
+			continue
+		}
+		name, param := rule, ""
+		if i := strings.Index(rule, "="); i >= 0 {
+			name, param = rule[:i], rule[i+1:]
+		}
+		if msg := field.Tag.Get(r.errTagPrefix + name); msg != "" {
+			descs = append(descs, msg)
+		}
+		switch name {
+		case "dive":
+			// rules after dive apply to the elements.
+			if target.Items != nil {
+				target = target.Items
+			} else if target.AdditionalProperties != nil {
+				target = target.AdditionalProperties
+			}
+		case "required", "exists":
+			if target == s {
+				required = true
+			}
+		case "len":
+			setBound(target, "min", param)
+			setBound(target, "max", param)
+		case "min", "gte":
+			setBound(target, "min", param)
+		case "max", "lte":
+			setBound(target, "max", param)
+		case "gt":
+			setBound(target, "gt", param)
+		case "lt":
+			setBound(target, "lt", param)
+		case "oneof":
+			for _, v := range strings.Fields(param) {
+				target.Enum = append(target.Enum, enumValue(target.Type, v))
+			}
+		case "email", "uuid", "ipv4", "ipv6", "hostname":
+			target.Format = name
+		case "url", "uri":
+			target.Format = "uri"
+		}
+	}
+	s.Description = strings.Join(descs, "; ")
+	return
+}
+
+// setBound - set the bound by the schema type,
+// lengths for string,item counts for array and values for number.
+func setBound(s *Schema, bound string, param string) {
+	switch s.Type {
+	case "string", "array":
+		n, err := strconv.Atoi(param)
+		if err != nil {
+			return
+		}
+		if bound == "gt" {
+			n, bound = n+1, "min"
+		}
+		if bound == "lt" {
+			n, bound = n-1, "max"
+		}
+		if s.Type == "string" {
+			if bound == "min" {
+				s.MinLength = &n
+			} else {
+				s.MaxLength = &n
+			}
+			return
+		}
+		if bound == "min" {
+			s.MinItems = &n
+		} else {
+			s.MaxItems = &n
+		}
+	case "integer", "number":
+		f, err := strconv.ParseFloat(param, 64)
+		if err != nil {
+			return
+		}
+		switch bound {
+		case "min":
+			s.Minimum = &f
+		case "max":
+			s.Maximum = &f
+		case "gt":
+			s.ExclusiveMinimum = &f
+		case "lt":
+			s.ExclusiveMaximum = &f
+		}
+	}
+}
+
+func enumValue(schemaType string, v string) interface{} {
+	switch schemaType {
+	case "integer", "number":
+		if f, err := strconv.ParseFloat(v, 64); err == nil {
+			return f
+		}
+	}
+	return v
+}
+
+// openAPIPath - convert the gin path params to the OpenAPI form,
+// e.g. "/students/:id" to "/students/{id}".
+func openAPIPath(p string) string {
+	segs := strings.Split(p, "/")
+	for i, seg := range segs {
+		if len(seg) > 1 && (seg[0] == ':' || seg[0] == '*') {
+			segs[i] = "{" + seg[1:] + "}"
+		}
+	}
+	return strings.Join(segs, "/")
+}
+
+func pathParams(p string) []string {
+	var names []string
+	for _, seg := range strings.Split(p, "/") {
+		if len(seg) > 1 && (seg[0] == ':' || seg[0] == '*') {
+			names = append(names, seg[1:])
+		}
+	}
+	return names
+}
+
+func sortedKeys(m map[string]*Schema) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func containsString(sl []string, s string) bool {
+	for _, v := range sl {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+func isSourceTag(tag string) bool {
+	return containsString(sourceTags, tag)
+}
+
+func hasSourceTag(field reflect.StructField) bool {
+	for _, tag := range sourceTags {
+		if fieldTagName(tag, field) != "" {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,146 @@
+// MIT License
+
+// Copyright (c) 2019 tanzy2018
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package groute
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// MIMEProblemJSON - Content-Type of the problem details,RFC 7807.
+const MIMEProblemJSON = "application/problem+json"
+
+// Problem - the problem details of RFC 7807.
+type Problem struct {
+	Type     string `json:"type"`
+	Title    string `json:"title"`
+	Status   int    `json:"status"`
+	Detail   string `json:"detail,omitempty"`
+	Instance string `json:"instance,omitempty"`
+	// Code - the extension of the business code set to Context.ErrCode.
+	Code interface{} `json:"code,omitempty"`
+	// Errors - the extension of the field errors.
+	Errors []ProblemField `json:"errors,omitempty"`
+	// Details - the extension of Error.Details.
+	Details interface{} `json:"details,omitempty"`
+}
+
+// ProblemField - error of one field of the request.
+type ProblemField struct {
+	Field    string `json:"field"`
+	Source   string `json:"source,omitempty"`
+	Expected string `json:"expected,omitempty"`
+	Value    string `json:"value,omitempty"`
+	Message  string `json:"message"`
+}
+
+// WithProblemDetails - respond the errors as `application/problem+json` by ProblemErrHandle.
+func WithProblemDetails() Option {
+	return func(opts *Options) {
+		opts.errHandle = ProblemErrHandle
+	}
+}
+
+// ProblemErrHandle - respond the error as the problem details with the HTTP status of the error:
+// the status of *Error,422 for the validation errors,400 for BindErrors,
+// 503 for *SaturatedError,504 for *TimeoutError,and 500 for the others.
+// The errors are found by errors.As,e.g. in StepErrors,
+// the errors of several steps respond the status they agree on,504 if any of them times out,otherwise 502.
+func ProblemErrHandle(c *Context, err interface{}) {
+	p := Problem{
+		Type:     "about:blank",
+		Status:   http.StatusInternalServerError,
+		Instance: c.GinContext.Request.URL.Path,
+		Code:     c.ErrCode,
+	}
+	if e, ok := asError(err); ok {
+		var code interface{}
+		p.Status, code, p.Detail = c.describeError(e)
+		if e.Code != 0 {
+			p.Code = code
+		}
+		p.Details = e.Details
+		fields := make([]string, 0, len(e.Fields))
+		for field := range e.Fields {
+			fields = append(fields, field)
+		}
+		sort.Strings(fields)
+		for _, field := range fields {
+			p.Errors = append(p.Errors, ProblemField{Field: field, Message: e.Fields[field]})
+		}
+		// handled already.
+		err = nil
+	}
+	switch e := err.(type) {
+	case map[string]string:
+		if ec, ok := c.errorCode(CodeValidation); ok {
+			p.Status, p.Detail = ec.Status, ec.Message
+		}
+		fields := make([]string, 0, len(e))
+		for field := range e {
+			fields = append(fields, field)
+		}
+		sort.Strings(fields)
+		for _, field := range fields {
+			p.Errors = append(p.Errors, ProblemField{Field: field, Message: e[field]})
+		}
+	case error:
+		p.Detail = e.Error()
+		if status, ok := errorStatus(e); ok {
+			p.Status = status
+		} else if ec, ok := c.errorCode(c.ErrCode); ok {
+			p.Status, p.Detail = ec.Status, ec.Message
+		}
+		var bindErrs BindErrors
+		if p.Status == http.StatusBadRequest && errors.As(e, &bindErrs) {
+			p.Detail = "the request params failed to be decoded"
+			for _, be := range bindErrs {
+				p.Errors = append(p.Errors, ProblemField{
+					Field:    be.Field,
+					Source:   be.Source,
+					Expected: be.Expected,
+					Value:    be.Value,
+					Message:  be.Error(),
+				})
+			}
+		}
+	case string:
+		p.Detail = e
+		if ec, ok := c.errorCode(c.ErrCode); ok {
+			p.Status, p.Detail = ec.Status, ec.Message
+		}
+	case []string:
+		p.Detail = strings.Join(e, "; ")
+	default:
+		if err != nil {
+			p.Detail = fmt.Sprint(err)
+		}
+	}
+	p.Title = http.StatusText(p.Status)
+	c.GinContext.Header("Content-Type", MIMEProblemJSON)
+	c.GinContext.JSON(p.Status, p)
+	c.GinContext.Abort()
+}
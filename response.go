@@ -0,0 +1,79 @@
+// MIT License
+
+// Copyright (c) 2019 tanzy2018
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package groute
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ResultHandleFunc - handle function returning the response and the error,
+// both are written by the ResponseEncoder of the router.
+type ResultHandleFunc func(*Context) (interface{}, error)
+
+// ResponseEncoder - write the response or the error returned by the ResultHandleFunc.
+type ResponseEncoder func(c *Context, rsp interface{}, err error)
+
+// NewResultInterface - create a new Interface instance handled by the ResultHandleFunc.
+func NewResultInterface(inter Interface, handle func(c *Context) (interface{}, error)) Interface {
+	inter.ResultHandle = handle
+	return inter
+}
+
+// WithResponseEncoder - set the encoder of the results of all the interfaces,default DefaultResponseEncoder.
+func WithResponseEncoder(encoder ResponseEncoder) Option {
+	return func(opts *Options) {
+		opts.responseEncoder = encoder
+	}
+}
+
+// DefaultResponseEncoder - the error goes to the ErrHandle,
+// the response is serialized as json unless the handle has written it already.
+func DefaultResponseEncoder(c *Context, rsp interface{}, err error) {
+	if err != nil {
+		c.ErrHandle(c, err)
+		return
+	}
+	if c.GinContext.Writer.Written() {
+		return
+	}
+	c.GinContext.JSON(http.StatusOK, rsp)
+}
+
+// EnvelopeResponseEncoder - the error goes to the ErrHandle,whose default envelope is {"state":0,"code":...,"msg":...},
+// the response is wrapped in the envelope {"state":1,"code":0,"data":...}.
+func EnvelopeResponseEncoder(c *Context, rsp interface{}, err error) {
+	if err != nil {
+		c.ErrHandle(c, err)
+		return
+	}
+	if c.GinContext.Writer.Written() {
+		return
+	}
+	c.GinContext.JSON(http.StatusOK, gin.H{
+		"state": 1,
+		"code":  0,
+		"data":  rsp,
+	})
+}
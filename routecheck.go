@@ -0,0 +1,181 @@
+// MIT License
+
+// Copyright (c) 2019 tanzy2018
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package groute
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// RouteErrors - errors of the interfaces,reported all at once.
+type RouteErrors []error
+
+func (e RouteErrors) Error() string {
+	msgs := make([]string, 0, len(e))
+	for _, err := range e {
+		msgs = append(msgs, err.Error())
+	}
+	return strings.Join(msgs, "; ")
+}
+
+func (e RouteErrors) Unwrap() []error {
+	return append([]error(nil), e...)
+}
+
+// Validate - check all the interfaces added to the router and report all the issues at once:
+// the interfaces rejected by AddE,empty paths,non-struct Param,
+// malformed validator tags and the error tags referring to no rule.
+// Call it after all the interfaces and the custom validations are registered.
+func (r *Router) Validate() error {
+	errs := append(RouteErrors(nil), r.rejected...)
+	for _, rt := range r.routes {
+		// check each interface once though it serves several methods.
+		if rt.method != rt.methods[0] {
+			continue
+		}
+		for _, issue := range r.checkInterface(rt.inter) {
+			errs = append(errs, fmt.Errorf("the interface [%s %s]: %s", strings.Join(rt.methods, ","), rt.inter.Path, issue))
+		}
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+func (r *Router) checkInterface(inter Interface) []string {
+	var issues []string
+	if inter.Path == "" {
+		issues = append(issues, "empty path")
+	}
+	if inter.Param == nil {
+		return issues
+	}
+	t := reflect.TypeOf(inter.Param)
+	if t.Kind() != reflect.Struct {
+		return append(issues, fmt.Sprintf("the Param %s is not a struct", t))
+	}
+	return append(issues, r.checkParam(t)...)
+}
+
+// checkParam - check the tags of the param and all the nested structs.
+func (r *Router) checkParam(t reflect.Type) []string {
+	var issues []string
+	visited := make(map[reflect.Type]bool)
+	malformed := make(map[string]bool)
+	var walk func(t reflect.Type)
+	walk = func(t reflect.Type) {
+		for t.Kind() == reflect.Ptr || t.Kind() == reflect.Slice || t.Kind() == reflect.Array || t.Kind() == reflect.Map {
+			t = t.Elem()
+		}
+		if t.Kind() != reflect.Struct || visited[t] {
+			return
+		}
+		visited[t] = true
+		// the nested structs are validated by the parent as well,report the same panic once.
+		if msg := r.checkValidatorTags(t); msg != "" && !malformed[msg] {
+			malformed[msg] = true
+			issues = append(issues, fmt.Sprintf("the validator tags of %s are malformed: %s", t, msg))
+		}
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			rules := ruleNames(field.Tag.Get(r.validatorTag))
+			for _, key := range tagKeys(field.Tag) {
+				if strings.HasPrefix(key, r.errTagPrefix) && !containsString(rules, strings.TrimPrefix(key, r.errTagPrefix)) {
+					issues = append(issues, fmt.Sprintf("the tag %s of the field %s.%s refers to no rule of the tag %s",
+						key, t.Name(), field.Name, r.validatorTag))
+				}
+			}
+			walk(field.Type)
+		}
+	}
+	walk(t)
+	return issues
+}
+
+// checkValidatorTags - validate the zero value so that the validator parses the tags,
+// returns the panic of the malformed tags.
+func (r *Router) checkValidatorTags(t reflect.Type) (msg string) {
+	defer func() {
+		if v := recover(); v != nil {
+			msg = fmt.Sprint(v)
+		}
+	}()
+	_ = r.structValidator.ValidateStruct(reflect.New(t).Interface())
+	return ""
+}
+
+// ruleNames - names of the rules in the validator tag,e.g. `required,max=10|eq=0` has required,max and eq.
+func ruleNames(tag string) []string {
+	var names []string
+	for _, rule := range strings.Split(tag, ",") {
+		for _, alt := range strings.Split(rule, "|") {
+			if i := strings.Index(alt, "="); i >= 0 {
+				alt = alt[:i]
+			}
+			if alt != "" {
+				names = append(names, alt)
+			}
+		}
+	}
+	return names
+}
+
+// tagKeys - keys of the struct tag,in the same way as reflect.StructTag.Lookup parses it.
+func tagKeys(tag reflect.StructTag) []string {
+	var keys []string
+	for tag != "" {
+		i := 0
+		for i < len(tag) && tag[i] == ' ' {
+			i++
+		}
+		tag = tag[i:]
+		if tag == "" {
+			break
+		}
+		i = 0
+		for i < len(tag) && tag[i] > ' ' && tag[i] != ':' && tag[i] != '"' && tag[i] != 0x7f {
+			i++
+		}
+		if i == 0 || i+1 >= len(tag) || tag[i] != ':' || tag[i+1] != '"' {
+			break
+		}
+		key := string(tag[:i])
+		tag = tag[i+1:]
+		// skip the quoted value.
+		i = 1
+		for i < len(tag) && tag[i] != '"' {
+			if tag[i] == '\\' {
+				i++
+			}
+			i++
+		}
+		if i >= len(tag) {
+			break
+		}
+		tag = tag[i+1:]
+		keys = append(keys, key)
+	}
+	return keys
+}
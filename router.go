@@ -24,15 +24,17 @@ package groute
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net/http"
+	"path"
 	"reflect"
 	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/gin-gonic/gin/binding"
-	validatorv8 "gopkg.in/go-playground/validator.v8"
-	validator "gopkg.in/go-playground/validator.v9"
+	ut "github.com/go-playground/universal-translator"
 )
 
 // ErrHandle - handle the validator error.
@@ -49,6 +51,43 @@ type Options struct {
 	errTagPrefix     string
 	clientContext    context.Context
 	validatorVersion string
+	validatorTag     string
+	locale           string
+	structValidator  binding.StructValidator
+	localeLookup     []string
+	openAPIInfo      OpenAPIInfo
+	openAPIPath      string
+	routesPath       string
+	convention       *Convention
+	routes           []route
+	// rejected - errors of the interfaces rejected by AddE.
+	rejected RouteErrors
+	// handled - all the routes registered to gin by the router.
+	handled         []route
+	stepTimeout     time.Duration
+	stepBudget      time.Duration
+	stepFailure     StepFailure
+	panicReporter   func(*Context, *PanicError)
+	stepConcurrency int
+	stepSaturation  Saturation
+	stepSemaphore   semaphore
+	stepMetrics     *stepMetrics
+	responseEncoder ResponseEncoder
+	errorCodes      *ErrorCodes
+}
+
+// route - interface registered by the router.
+type route struct {
+	method string
+	// methods - all the methods of the interface.
+	methods []string
+	// path - full path including the prefix of the router group.
+	path  string
+	inter Interface
+	// steps - names of the steps.
+	steps []string
+	// source - the struct method returning the interface,empty if added directly.
+	source string
 }
 
 // WithRouter - set the route.
@@ -91,11 +130,31 @@ func WithClientContext(ctx context.Context) Option {
 
 // WithVaidatorV9 - set validator v9
 // supported locale:en,fr,id,ja,nl,pt_BR,tr,zh,zh_tw;default en
+// the locale is the default one when the request doesn't negotiate a supported locale,
+// see WithLocaleLookup.
 func WithVaidatorV9(locale string) Option {
-	defaultLocale = locale
-	binding.Validator = new(defaultValidator)
 	return func(opts *Options) {
 		opts.validatorVersion = "v9"
+		opts.locale = locale
+	}
+}
+
+// WithValidatorV10 - set validator v10,the rules and the error hints are the same as v9,
+// the rules only supported by v8 are aliased,e.g. `exists` to `required`.
+// supported locale:en,fr,id,ja,nl,pt_BR,tr,zh,zh_tw;default en
+func WithValidatorV10(locale string) Option {
+	return func(opts *Options) {
+		opts.validatorVersion = "v10"
+		opts.locale = locale
+	}
+}
+
+// WithValidatorTagName - set the tag of the validation rules,default "binding".
+func WithValidatorTagName(name string) Option {
+	return func(opts *Options) {
+		if name != "" {
+			opts.validatorTag = name
+		}
 	}
 }
 
@@ -119,12 +178,45 @@ func getTagByContentType(contentType string) string {
 
 // DefaulErrHandle -  handler error when validator throw exception.
 func defaulErrHandle(c *Context, err interface{}) {
+	if e, ok := asError(err); ok {
+		_, code, msg := c.describeError(e)
+		rsp := gin.H{
+			"state": 0,
+			"code":  code,
+			"msg":   msg,
+		}
+		if len(e.Fields) > 0 {
+			rsp["msg"] = e.Fields
+		}
+		if e.Details != nil {
+			rsp["details"] = e.Details
+		}
+		c.GinContext.JSON(http.StatusOK, rsp)
+		c.GinContext.Abort()
+		return
+	}
 	var msg interface{}
+	// default code :request params failed to exam.
+	var code interface{} = CodeValidation
+	// plain - the error without the code of its own.
+	var plain bool
 	switch err.(type) {
+	case error:
+		e := err.(error)
+		msg = e.Error()
+		// the errors of the steps and the binding.
+		if status, ok := errorStatus(e); ok {
+			code = status
+			var bindErrs BindErrors
+			if status == http.StatusBadRequest && errors.As(e, &bindErrs) {
+				msg = bindErrs
+			}
+		} else {
+			plain = true
+		}
 	case string:
 		msg = err.(string)
-	case error:
-		msg = err.(error).Error()
+		plain = true
 	case []interface{}:
 		msg = err.([]interface{})
 	case map[string]string:
@@ -132,11 +224,12 @@ func defaulErrHandle(c *Context, err interface{}) {
 	case []string:
 		msg = err.([]string)
 	}
-	var code interface{}
-	// default code :request params failed to exam.
-	code = 402
 	if c.ErrCode != nil {
 		code = c.ErrCode
+		// the message of the registered code.
+		if ec, ok := c.errorCode(code); ok && plain {
+			msg = ec.Message
+		}
 	}
 	c.GinContext.JSON(http.StatusOK, gin.H{
 		"state": 0,
@@ -153,31 +246,120 @@ type Router struct {
 
 // NewRouter create a new router
 func NewRouter(options ...Option) Router {
+	r, err := NewRouterE(options...)
+	if err != nil {
+		panic(err)
+	}
+	return r
+}
+
+// NewRouterE - same as NewRouter but returns the error instead of panic.
+func NewRouterE(options ...Option) (Router, error) {
 	opts := &Options{
 		errHandle:        defaulErrHandle,
 		errTagPrefix:     "err-",
 		validatorVersion: "v8",
+		validatorTag:     "binding",
+		locale:           "en",
+		localeLookup:     []string{"header:Accept-Language"},
+		stepFailure:      StepFailureWait,
+		stepSaturation:   SaturationWait,
+		stepMetrics:      &stepMetrics{},
+		responseEncoder:  DefaultResponseEncoder,
+		errorCodes:       defaultErrorCodes,
 	}
 	for _, op := range options {
 		op(opts)
 	}
 	if opts.router == nil {
-		panic("gin router must be set and not be nil")
+		return Router{}, errors.New("gin router must be set and not be nil")
 	}
-	return Router{
+	opts.structValidator = newStructValidator(opts.validatorVersion, opts.locale, opts.validatorTag)
+	opts.stepSemaphore = newSemaphore(opts.stepConcurrency)
+	r := Router{
 		opts,
 	}
+	if opts.openAPIPath != "" {
+		if err := r.serveOpenAPI(opts.openAPIPath); err != nil {
+			return Router{}, err
+		}
+	}
+	if opts.routesPath != "" {
+		if err := r.serveRoutes(opts.routesPath); err != nil {
+			return Router{}, err
+		}
+	}
+	return r, nil
 }
 
-// add to router
-func (r *Router) addInterface(inter Interface) {
-	if inter.Method == "" {
-		inter.Method = "POST"
+// add to router,source is the struct method returning the interface.
+func (r *Router) addInterface(inter Interface, source string) error {
+	methods, err := interfaceMethods(inter)
+	if err != nil {
+		return fmt.Errorf("the interface [%s]: %w", inter.Path, err)
+	}
+	// the param of the typed interface is bound only if it's a struct.
+	if inter.response != nil && kindOf(reflect.TypeOf(inter.Param)) != reflect.Struct {
+		return fmt.Errorf("the interface [%s %s]: the Param %T of the typed interface is not a struct",
+			strings.Join(methods, ","), inter.Path, inter.Param)
+	}
+	fullPath := r.fullPath(inter.Path)
+	for _, method := range methods {
+		for _, rt := range r.routes {
+			if rt.method == method && rt.path == fullPath {
+				return fmt.Errorf("the interface [%s %s]: duplicated with the interface added before", method, inter.Path)
+			}
+		}
+	}
+	inter.Method = methods[0]
+	if inter.ResultHandle != nil {
+		handle, encode := inter.ResultHandle, r.responseEncoder
+		inter.Handle = func(c *Context) {
+			rsp, err := handle(c)
+			encode(c, rsp, err)
+		}
+	}
+	if inter.Handle == nil {
+		return fmt.Errorf("the interface [%s %s]: no Handle", strings.Join(methods, ","), inter.Path)
+	}
+	stepOpts := stepOptions{
+		timeout:       r.stepTimeout,
+		budget:        r.stepBudget,
+		failure:       r.stepFailure,
+		panicReporter: r.panicReporter,
+	}
+	if inter.StepTimeout > 0 {
+		stepOpts.timeout = inter.StepTimeout
+	}
+	if inter.StepBudget > 0 {
+		stepOpts.budget = inter.StepBudget
+	}
+	if inter.StepFailure > 0 {
+		stepOpts.failure = inter.StepFailure
+	}
+	saturation := r.stepSaturation
+	if inter.StepSaturation > 0 {
+		saturation = inter.StepSaturation
+	}
+	stepOpts.limiter = newLimiter(r.stepMetrics, saturation, newSemaphore(inter.StepConcurrency), r.stepSemaphore)
+	interSteps := inter.steps()
+	stepNames := make([]string, 0, len(interSteps))
+	for _, step := range interSteps {
+		stepNames = append(stepNames, step.Name)
+	}
+	steps, err := newStepGraph(interSteps, stepOpts)
+	if err != nil {
+		return fmt.Errorf("the interface [%s %s]: %w", strings.Join(methods, ","), inter.Path, err)
 	}
 	hdlf := func(c *gin.Context) {
 
 		req := &Context{
 			GinContext: c,
+			Locale:     resolveLocale(c, r.localeLookup),
+			ErrorCodes: r.errorCodes,
+		}
+		if req.Locale == "" {
+			req.Locale = r.locale
 		}
 		if inter.ErrHandle != nil {
 			req.ErrHandle = inter.ErrHandle
@@ -187,141 +369,221 @@ func (r *Router) addInterface(inter Interface) {
 
 		if inter.Param != nil {
 			req.Param = reflect.New(reflect.TypeOf(inter.Param)).Interface()
-			if err := c.ShouldBind(req.Param); err != nil {
-				var errMap map[string]string
-
-				switch r.validatorVersion {
-				// handle validator v9
-				case "v9":
-					if v, ok := err.(validator.ValidationErrors); ok {
-						pType := reflect.TypeOf(inter.Param)
-						errMap = make(map[string]string, len(v))
-						tagType := getTagByContentType(c.GetHeader("Content-Type"))
-						if tagType == "" {
-							req.ErrHandle(req, fmt.Sprintf("unsupported Content-Type:%s", c.GetHeader("Content-Type")))
-							return
+			bodyTag, err := bindRequest(c, req.Param)
+			if err != nil {
+				req.ErrHandle(req, err)
+				return
+			}
+			errMap := make(map[string]string)
+			if err := r.structValidator.ValidateStruct(req.Param); err != nil {
+				if errs, ok := fieldErrors(err, r.translator(req.Locale)); ok {
+					if bodyTag == "" {
+						req.ErrHandle(req, fmt.Sprintf("unsupported Content-Type:%s", c.GetHeader("Content-Type")))
+						return
+					}
+					pType := reflect.TypeOf(inter.Param)
+					for _, e := range errs {
+						fieldTag, structField, ok := errFieldPath(pType, bodyTag, e.namespace)
+						if !ok {
+							continue
 						}
-						for _, e := range v {
-							structField, ok := pType.FieldByName(e.Field())
-							if !ok {
-								continue
-							}
-
-							errmsg := structField.Tag.Get(r.errTagPrefix + e.Tag())
-							if errmsg == "" {
-								errmsg = e.Translate(translator)
-							}
-							fieldTag := fieldTagName(tagType, structField)
-							errMap[fieldTag] = errmsg
+						errmsg := structField.Tag.Get(r.errTagPrefix + e.tag)
+						if errmsg == "" {
+							errmsg = e.message
 						}
-					}
-				// handle validator v8 same as default.
-				case "v8":
-					fallthrough
-				default:
-					if v, ok := err.(validatorv8.ValidationErrors); ok {
-						pType := reflect.TypeOf(inter.Param)
-						errMap = make(map[string]string, len(v))
-						tagType := getTagByContentType(c.GetHeader("Content-Type"))
-						if tagType == "" {
-							req.ErrHandle(req, fmt.Sprintf("unsupported Content-Type:%s", c.GetHeader("Content-Type")))
-							return
+						if errmsg == "" {
+							errmsg = fmt.Sprintf(
+								"param '%s' with value '%v' failed on the validation tag '%s'",
+								fieldTag,
+								e.value,
+								e.tag,
+							)
 						}
-						for _, e := range v {
-
-							structField, ok := pType.FieldByName(e.Field)
-							if !ok {
-								continue
-							}
-
-							errmsg := structField.Tag.Get(r.errTagPrefix + e.Tag)
-							if errmsg == "" {
-								errmsg = fmt.Sprintf(
-									"param '%s' with value '%v' failed on the validation tag '%s'",
-									fieldTagName(tagType, structField),
-									e.Value,
-									e.Tag,
-								)
-							}
-							fieldTag := fieldTagName(tagType, structField)
+						if _, ok := errMap[fieldTag]; !ok {
 							errMap[fieldTag] = errmsg
 						}
 					}
 				}
-				if len(errMap) != 0 {
-					req.ErrHandle(req, errMap)
-					return
-				}
+			}
+			if len(errMap) != 0 {
+				req.ErrHandle(req, errMap)
+				return
 			}
 		}
 
 		// handle the asynchronous middleware
+		stepsContext := req.GinContext.Request.Context()
 		if r.clientContext != nil {
 			req.ClientContext = r.clientContext
+			var cancel context.CancelFunc
+			stepsContext, cancel = mergeContext(r.clientContext, stepsContext)
+			defer cancel()
 		} else {
 			req.ClientContext = req.GinContext
 		}
 
-		if len(inter.AsyncHandleFunc) == 1 {
-			if err := inter.AsyncHandleFunc[0](req); err != nil {
-				req.ErrHandle(req, err)
-				return
-			}
+		// handle the steps
+		if err := steps.run(stepsContext, req); err != nil {
+			req.ErrHandle(req, err)
+			return
 		}
+		inter.Handle(req)
+	}
 
-		if len(inter.AsyncHandleFunc) > 1 {
-			ctx, cancel := context.WithCancel(req.GinContext)
-			handleLen := len(inter.AsyncHandleFunc)
-			errChan := make(chan error, 1)
-			for _, fn := range inter.AsyncHandleFunc {
-				fn := fn
-				wrap(ctx, func(ctx context.Context) {
-					select {
-					case <-ctx.Done():
-					case errChan <- fn(req):
-					}
-				})
-			}
-			if err := <-firstError(errChan, handleLen, cancel); err != nil {
-				req.ErrHandle(req, err)
-				return
+	hdlfs := append(append([]gin.HandlerFunc(nil), r.middleware...), hdlf)
+	// check all the methods first so that none of them is registered if any conflicts.
+	for _, method := range methods {
+		if err := r.checkConflict(method, fullPath); err != nil {
+			return fmt.Errorf("the interface [%s %s]: %w", method, inter.Path, err)
+		}
+	}
+	for _, method := range methods {
+		if err := r.handle(method, inter.Path, hdlfs...); err != nil {
+			// conflicts with the routes added to gin directly,the former methods stay registered.
+			return fmt.Errorf("the interface [%s %s]: %w", method, inter.Path, err)
+		}
+		r.routes = append(r.routes, route{
+			method:  method,
+			methods: methods,
+			path:    fullPath,
+			inter:   inter,
+			steps:   stepNames,
+			source:  source,
+		})
+	}
+	return nil
+}
+
+// handle - register to gin,returns the error instead of the panic of gin,
+// e.g. the path conflicts with the others.
+func (r *Router) handle(method, relativePath string, handlers ...gin.HandlerFunc) error {
+	if err := tryHandle(r.router, method, relativePath, handlers...); err != nil {
+		return err
+	}
+	r.handled = append(r.handled, route{method: method, path: r.fullPath(relativePath)})
+	return nil
+}
+
+func tryHandle(router gin.IRoutes, method, path string, handlers ...gin.HandlerFunc) (err error) {
+	defer func() {
+		if v := recover(); v != nil {
+			err = fmt.Errorf("%v", v)
+		}
+	}()
+	router.Handle(method, path, handlers...)
+	return nil
+}
+
+// checkConflict - check the path against the routes registered by the router by the rules of the tree of gin:
+// a wildcard can't share its position with a static path or another wildcard,
+// and a catch-all can't follow the path registered with the same segment root.
+func (r *Router) checkConflict(method, fullPath string) error {
+	for _, rt := range r.handled {
+		if rt.method == method && pathsConflict(rt.path, fullPath) {
+			return fmt.Errorf("the path conflicts with the route [%s %s] registered before", rt.method, rt.path)
+		}
+	}
+	return nil
+}
+
+func pathsConflict(a, b string) bool {
+	i := 0
+	for i < len(a) && i < len(b) {
+		if isWildcard(a[i]) || isWildcard(b[i]) {
+			name := wildcardName(a[i:])
+			if a[i] != b[i] || name != wildcardName(b[i:]) {
+				return true
 			}
+			i += len(name)
+			continue
 		}
-		// handle the synchronous middleware
-		if len(inter.SyncHandleFunc) > 0 {
-			for _, fn := range inter.SyncHandleFunc {
-				if err := fn(req); err != nil {
-					req.ErrHandle(req, err)
-					return
-				}
+		if a[i] != b[i] {
+			return false
+		}
+		i++
+	}
+	if len(a) == len(b) {
+		return true
+	}
+	if len(a) > len(b) {
+		a, b = b, a
+	}
+	return b[len(a)] == '*'
+}
+
+func isWildcard(c byte) bool {
+	return c == ':' || c == '*'
+}
+
+// wildcardName - the wildcard at the start of the path until the next segment,e.g. :id.
+func wildcardName(path string) string {
+	if i := strings.IndexByte(path, '/'); i >= 0 {
+		return path[:i]
+	}
+	return path
+}
+
+// anyMethods - the methods of `ANY`,same as gin.
+var anyMethods = []string{
+	http.MethodGet, http.MethodPost, http.MethodPut, http.MethodPatch,
+	http.MethodHead, http.MethodOptions, http.MethodDelete, http.MethodConnect, http.MethodTrace,
+}
+
+// interfaceMethods - the HTTP methods of the interface,default POST,
+// `ANY` expands to all the methods and the unknown ones are rejected.
+func interfaceMethods(inter Interface) ([]string, error) {
+	names := inter.Methods
+	if inter.Method != "" {
+		names = append([]string{inter.Method}, names...)
+	}
+	if len(names) == 0 {
+		return []string{http.MethodPost}, nil
+	}
+	var methods []string
+	for _, name := range names {
+		method := strings.ToUpper(strings.TrimSpace(name))
+		expanded := []string{method}
+		if method == "ANY" {
+			expanded = anyMethods
+		} else if !containsString(anyMethods, method) {
+			return nil, fmt.Errorf("unknown method %q", name)
+		}
+		for _, m := range expanded {
+			if !containsString(methods, m) {
+				methods = append(methods, m)
 			}
 		}
-		inter.Handle(req)
 	}
+	return methods, nil
+}
 
-	method := r.router.POST
-	switch strings.ToLower(inter.Method) {
-	case "get":
-		method = r.router.GET
-	case "post":
-		method = r.router.POST
-	case "put":
-		method = r.router.PUT
-	case "delete":
-		method = r.router.DELETE
-	case "patch":
-		method = r.router.PATCH
-	case "head":
-		method = r.router.HEAD
-	default:
+// Validator - the validator of this router,
+// Engine() returns the validator engine of the version set by the options.
+func (r *Router) Validator() binding.StructValidator {
+	return r.structValidator
+}
+
+// translator - translator of the locale,nil if the validator can't translate.
+func (r *Router) translator(locale string) ut.Translator {
+	if v, ok := r.structValidator.(interface {
+		translator(locale string) ut.Translator
+	}); ok {
+		return v.translator(locale)
 	}
+	return nil
+}
 
-	if len(r.middleware) > 0 {
-		hdlfs := append(r.middleware, hdlf)
-		method(inter.Path, hdlfs...)
-	} else {
-		method(inter.Path, hdlf)
+// fullPath - join the path with the base path of the router group.
+func (r *Router) fullPath(relativePath string) string {
+	group, ok := r.router.(interface{ BasePath() string })
+	if !ok {
+		return relativePath
 	}
+	finalPath := path.Join(group.BasePath(), relativePath)
+	if strings.HasSuffix(relativePath, "/") && !strings.HasSuffix(finalPath, "/") {
+		finalPath += "/"
+	}
+	return finalPath
 }
 
 func fieldTagName(tagType string, field reflect.StructField) string {
@@ -333,51 +595,73 @@ func fieldTagName(tagType string, field reflect.StructField) string {
 }
 
 // auto register all the exported function to the route
-func (r *Router) addStruct(in interface{}) {
+func (r *Router) addStruct(in interface{}) error {
 	t := reflect.TypeOf(in)
-	if t.Kind() != reflect.Ptr || t.Elem().Kind() != reflect.Struct {
-		err := fmt.Errorf("the given interface [realType:%T,baseType:%s] is not a pointer of struct", in, t.Kind())
-		panic(err)
+	if t == nil || t.Kind() != reflect.Ptr || t.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("the given interface [realType:%T,baseType:%v] is not a pointer of struct", in, kindOf(t))
 	}
+	var errs RouteErrors
 	val := reflect.ValueOf(in)
 	for i := 0; i < t.NumMethod(); i++ {
 		m := t.Method(i)
-		if m.Type.NumOut() == 1 && m.Type.Out(0) == reflect.TypeOf(Interface{}) {
-			r.addInterface(val.Method(m.Index).Call(nil)[0].Interface().(Interface))
+		if m.Type.NumIn() != 1 || m.Type.NumOut() != 1 {
+			continue
 		}
+		var inter Interface
+		switch out := m.Type.Out(0); {
+		case out == reflect.TypeOf(Interface{}):
+			inter = val.Method(m.Index).Call(nil)[0].Interface().(Interface)
+		case out.Implements(interfacerType):
+			inter = val.Method(m.Index).Call(nil)[0].Interface().(interfacer).Interface()
+		default:
+			continue
+		}
+		if r.convention != nil {
+			inter = r.convention.apply(inter, t.Elem().Name(), m.Name)
+		}
+		if err := r.addInterface(inter, fmt.Sprintf("(%s).%s", t, m.Name)); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if len(errs) > 0 {
+		return errs
 	}
+	return nil
+}
+
+func kindOf(t reflect.Type) interface{} {
+	if t == nil {
+		return nil
+	}
+	return t.Kind()
 }
 
 // Add add route
 func (r *Router) Add(in interface{}) {
-	switch in.(type) {
-	case Interface:
-		r.addInterface(in.(Interface))
-	default:
-		r.addStruct(in)
+	if err := r.add(in); err != nil {
+		panic(err)
 	}
 }
 
-func wrap(ctx context.Context, f func(context.Context)) {
-	go f(ctx)
+// AddE - same as Add but returns the error instead of panic,
+// the error is reported by Validate as well.
+func (r *Router) AddE(in interface{}) error {
+	err := r.add(in)
+	if errs, ok := err.(RouteErrors); ok {
+		r.rejected = append(r.rejected, errs...)
+	} else if err != nil {
+		r.rejected = append(r.rejected, err)
+	}
+	return err
 }
 
-func firstError(in chan error, rounds int, cancel func()) <-chan error {
-	out := make(chan error, 1)
-	i := 0
-	for err := range in {
-		if err != nil {
-			cancel()
-			out <- err
-			close(out)
-			return out
-		}
-		i++
-		if i >= rounds {
-			break
-		}
+func (r *Router) add(in interface{}) error {
+	switch in.(type) {
+	case Interface:
+		return r.addInterface(in.(Interface), "")
+	case interfacer:
+		return r.addInterface(in.(interfacer).Interface(), "")
+	default:
+		return r.addStruct(in)
 	}
-	out <- nil
-	close(out)
-	return out
 }
@@ -29,14 +29,22 @@ import (
 	"fmt"
 	"log"
 	"math/rand"
+	"net"
 	"net/http"
+	"net/http/httptest"
 	"os"
+	"reflect"
+	"runtime"
 	"sort"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	validatorv10 "github.com/go-playground/validator/v10"
 
 	"github.com/levigross/grequests"
 	"github.com/stretchr/testify/assert"
@@ -44,7 +52,9 @@ import (
 )
 
 const (
-	baseTestURL = "http://localhost"
+	baseTestURL  = "http://localhost"
+	testAddr     = ":80"
+	testDialAddr = "localhost:80"
 )
 
 var (
@@ -72,7 +82,20 @@ func (r routerTestObj) run() {
 	if testEngin == nil {
 		panic("gin.Engine is not initialized")
 	}
-	go testEngin.Run()
+	go testEngin.Run(testAddr)
+	waitForTestServer()
+}
+
+// wait until the test server accepts connections.
+func waitForTestServer() {
+	for i := 0; i < 100; i++ {
+		conn, err := net.Dial("tcp", testDialAddr)
+		if err == nil {
+			conn.Close()
+			return
+		}
+		time.Sleep(time.Millisecond * 10)
+	}
 }
 
 func (r routerTestObj) add(group string, route interface{}, middleware ...gin.HandlerFunc) {
@@ -339,6 +362,1969 @@ func TestRouterParamValidate(t *testing.T) {
 	}
 }
 
+type typedParams struct {
+	Name string `form:"name" json:"name" binding:"required" err-required:"name is required"`
+	Age  int    `form:"age" json:"age"`
+}
+
+type typedResponse struct {
+	Greeting string `json:"greeting"`
+	Age      int    `json:"age"`
+}
+
+type TestTypedRouter struct{}
+
+func (tr *TestTypedRouter) Greet() TypedInterface[typedParams, typedResponse] {
+	return NewTypedInterface(
+		TypedInterface[typedParams, typedResponse]{
+			Path:   "/router-typed-struct",
+			Method: "GET",
+		},
+		func(c *Context, p *typedParams) (typedResponse, error) {
+			return typedResponse{Greeting: "hi " + p.Name, Age: p.Age}, nil
+		},
+	)
+}
+
+func TestTypedInterface(t *testing.T) {
+	hd := NewTypedInterface(
+		TypedInterface[typedParams, typedResponse]{
+			Path:   "/router-typed",
+			Method: "GET",
+		},
+		func(c *Context, p *typedParams) (typedResponse, error) {
+			if p.Age < 0 {
+				c.ErrCode = 403
+				return typedResponse{}, errors.New("age must not be negative")
+			}
+			return typedResponse{Greeting: "hello " + p.Name, Age: p.Age}, nil
+		},
+	)
+	var r routerTestObj
+	r.add("/", hd)
+	r.add("/", &TestTypedRouter{})
+	r.run()
+
+	eles := []map[string]string{
+		map[string]string{
+			"url":      "/router-typed?name=Lin&age=18",
+			"expected": "{\"greeting\":\"hello Lin\",\"age\":18}",
+		},
+		map[string]string{
+			"url":      "/router-typed?age=18",
+			"expected": "{\"code\":402,\"msg\":{\"name\":\"name is required\"},\"state\":0}",
+		},
+		map[string]string{
+			"url":      "/router-typed?name=Lin&age=-1",
+			"expected": "{\"code\":403,\"msg\":\"age must not be negative\",\"state\":0}",
+		},
+		map[string]string{
+			"url":      "/router-typed-struct?name=Tan",
+			"expected": "{\"greeting\":\"hi Tan\",\"age\":0}",
+		},
+	}
+	for _, ele := range eles {
+		rsp, err := grequests.Get(baseTestURL+ele["url"], nil)
+		if err != nil {
+			t.Fatalf("grequests.Get:%s", ele["url"])
+		}
+		assert.Equal(t, true, rsp.Ok)
+		assert.Equal(t, ele["expected"], rsp.String())
+		rsp.Close()
+	}
+
+	router := NewRouter(WithRouter(gin.New().Group("/")))
+	err := router.AddE(NewTypedInterface(
+		TypedInterface[*typedParams, typedResponse]{Path: "/typed-ptr", Method: "GET"},
+		func(c *Context, p **typedParams) (typedResponse, error) { return typedResponse{}, nil },
+	))
+	assert.Equal(t, "the interface [GET /typed-ptr]: the Param *groute_test.typedParams of the typed interface is not a struct", err.Error())
+	err = router.AddE(NewTypedInterface(
+		TypedInterface[int, typedResponse]{Path: "/typed-int", Method: "GET"},
+		func(c *Context, p *int) (typedResponse, error) { return typedResponse{}, nil },
+	))
+	assert.Equal(t, "the interface [GET /typed-int]: the Param int of the typed interface is not a struct", err.Error())
+	assert.Equal(t, 0, len(router.Routes()))
+}
+
+func TestOpenAPI(t *testing.T) {
+	type Student struct {
+		ID     int      `form:"id" json:"id" binding:"required,min=10" err-required:"id is required" err-min:"id must be greater than 10 or equal to 10"`
+		Name   string   `form:"name" json:"name" binding:"max=20"`
+		Gender string   `form:"gender" json:"gender" binding:"oneof=male female"`
+		Tags   []string `form:"tags" json:"tags" binding:"len=2"`
+	}
+	engin := gin.New()
+	api := NewRouter(
+		WithRouter(engin.Group("/api")),
+		WithOpenAPIInfo(OpenAPIInfo{Title: "students", Version: "0.1.0"}),
+		WithOpenAPIPath("/openapi.json"),
+	)
+	api.Add(NewInterface(Interface{Path: "/students", Method: "GET", Param: Student{}}, func(c *Context) {}))
+	api.Add(NewInterface(Interface{Path: "/students/:id", Method: "PUT", Param: Student{}}, func(c *Context) {}))
+	api.Add(NewTypedInterface(
+		TypedInterface[typedParams, typedResponse]{Path: "/greet", Method: "GET"},
+		func(c *Context, p *typedParams) (typedResponse, error) {
+			return typedResponse{}, nil
+		},
+	))
+
+	doc := api.OpenAPI()
+	assert.Equal(t, "3.1.0", doc.OpenAPI)
+	assert.Equal(t, "students", doc.Info.Title)
+
+	get := doc.Paths["/api/students"]["get"]
+	if assert.NotNil(t, get) {
+		assert.Nil(t, get.RequestBody)
+		assert.Equal(t, 4, len(get.Parameters))
+		id := get.Parameters[1]
+		assert.Equal(t, "id", id.Name)
+		assert.Equal(t, "query", id.In)
+		assert.Equal(t, true, id.Required)
+		assert.Equal(t, "id is required; id must be greater than 10 or equal to 10", id.Description)
+		assert.Equal(t, float64(10), *id.Schema.Minimum)
+		gender := get.Parameters[0]
+		assert.Equal(t, []interface{}{"male", "female"}, gender.Schema.Enum)
+	}
+
+	put := doc.Paths["/api/students/{id}"]["put"]
+	if assert.NotNil(t, put) {
+		assert.Equal(t, "id", put.Parameters[0].Name)
+		assert.Equal(t, "path", put.Parameters[0].In)
+		body := put.RequestBody.Content[gin.MIMEJSON].Schema
+		assert.Equal(t, []string{"id"}, body.Required)
+		assert.Equal(t, 20, *body.Properties["name"].MaxLength)
+		assert.Equal(t, 2, *body.Properties["tags"].MinItems)
+		assert.Equal(t, 2, *body.Properties["tags"].MaxItems)
+	}
+
+	greet := doc.Paths["/api/greet"]["get"]
+	if assert.NotNil(t, greet) {
+		rsp := greet.Responses["200"].Content[gin.MIMEJSON].Schema
+		assert.Equal(t, "string", rsp.Properties["greeting"].Type)
+		assert.Equal(t, "integer", rsp.Properties["age"].Type)
+	}
+
+	w := httptest.NewRecorder()
+	engin.ServeHTTP(w, httptest.NewRequest("GET", "/api/openapi.json", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	var served map[string]interface{}
+	json.Unmarshal(w.Body.Bytes(), &served)
+	assert.Equal(t, "3.1.0", served["openapi"])
+	assert.Equal(t, 3, len(served["paths"].(map[string]interface{})))
+}
+
+func TestRouterURIParam(t *testing.T) {
+	type Score struct {
+		ID     int    `uri:"id" binding:"min=10" err-min:"id must be greater than 10 or equal to 10"`
+		Course string `uri:"course" binding:"oneof=math english" err-oneof:"course must be math or english"`
+		Name   string `form:"name" json:"name" binding:"required" err-required:"name is required"`
+	}
+	hd := NewInterface(
+		Interface{
+			Path:   "/router-uri/:id/scores/:course",
+			Param:  Score{},
+			Method: "GET",
+		},
+		func(c *Context) {
+			p := c.Param.(*Score)
+			c.GinContext.String(http.StatusOK, fmt.Sprintf("id:%d;course:%s;name:%s", p.ID, p.Course, p.Name))
+		},
+	)
+	var r routerTestObj
+	r.runWithAdd("/", hd)
+
+	eles := []map[string]string{
+		map[string]string{
+			"url":      "/router-uri/12/scores/math?name=Lin",
+			"expected": "id:12;course:math;name:Lin",
+		},
+		map[string]string{
+			"url":      "/router-uri/5/scores/art",
+			"expected": "{\"code\":402,\"msg\":{\"course\":\"course must be math or english\",\"id\":\"id must be greater than 10 or equal to 10\",\"name\":\"name is required\"},\"state\":0}",
+		},
+		map[string]string{
+			"url":      "/router-uri/abc/scores/math?name=Lin",
+			"expected": "{\"code\":400,\"msg\":[{\"field\":\"id\",\"source\":\"uri\",\"expected\":\"int\",\"value\":\"abc\",\"msg\":\"param 'id' with value 'abc' is not a valid int\"}],\"state\":0}",
+		},
+	}
+	for _, ele := range eles {
+		rsp, err := grequests.Get(baseTestURL+ele["url"], nil)
+		if err != nil {
+			t.Fatalf("grequests.Get:%s", ele["url"])
+		}
+		assert.Equal(t, true, rsp.Ok)
+		assert.Equal(t, ele["expected"], rsp.String())
+		rsp.Close()
+	}
+}
+
+func TestRouterMultiSource(t *testing.T) {
+	type Params struct {
+		Org     string `uri:"org"`
+		Tenant  string `header:"X-Tenant-Id" binding:"required" err-required:"tenant is required"`
+		Page    int    `query:"page" binding:"min=1" err-min:"page must be greater than 0"`
+		Lang    string `query:"lang" header:"X-Lang"`
+		Session string `cookie:"session"`
+		Name    string `json:"name" binding:"required" err-required:"name is required"`
+	}
+	hd := NewInterface(
+		Interface{
+			Path:   "/router-multi-source/:org",
+			Param:  Params{},
+			Method: "POST",
+		},
+		func(c *Context) {
+			p := c.Param.(*Params)
+			c.GinContext.String(http.StatusOK, fmt.Sprintf("org:%s;tenant:%s;page:%d;lang:%s;session:%s;name:%s",
+				p.Org, p.Tenant, p.Page, p.Lang, p.Session, p.Name))
+		},
+	)
+	var r routerTestObj
+	r.runWithAdd("/", hd)
+
+	rsp, err := grequests.Post(baseTestURL+"/router-multi-source/acme", &grequests.RequestOptions{
+		Params:  map[string]string{"page": "2", "lang": "en"},
+		Headers: map[string]string{"X-Tenant-Id": "t-1", "X-Lang": "zh"},
+		Cookies: []*http.Cookie{&http.Cookie{Name: "session", Value: "s-1"}},
+		JSON:    map[string]string{"name": "Lin"},
+	})
+	if err != nil {
+		t.Fatal("grequests.Post:", err)
+	}
+	assert.Equal(t, true, rsp.Ok)
+	assert.Equal(t, "org:acme;tenant:t-1;page:2;lang:zh;session:s-1;name:Lin", rsp.String())
+	rsp.Close()
+
+	rsp, err = grequests.Post(baseTestURL+"/router-multi-source/acme", &grequests.RequestOptions{
+		Params: map[string]string{"page": "0"},
+		JSON:   map[string]string{},
+	})
+	if err != nil {
+		t.Fatal("grequests.Post:", err)
+	}
+	assert.Equal(t, true, rsp.Ok)
+	assert.Equal(t, "{\"code\":402,\"msg\":{\"X-Tenant-Id\":\"tenant is required\",\"name\":\"name is required\",\"page\":\"page must be greater than 0\"},\"state\":0}", rsp.String())
+	rsp.Close()
+}
+
+func TestRouterLocale(t *testing.T) {
+	type Params struct {
+		Lin string `form:"lin" json:"lin" binding:"required"`
+	}
+	hd := NewInterface(
+		Interface{
+			Path:   "/router-locale",
+			Param:  Params{},
+			Method: "GET",
+		},
+		func(c *Context) {
+			c.GinContext.String(http.StatusOK, "locale:"+c.Locale)
+		},
+	)
+	var r routerTestObj
+	r.runWithAdd("/", hd)
+
+	eles := []map[string]string{
+		map[string]string{
+			"lang":     "",
+			"expected": "{\"code\":402,\"msg\":{\"lin\":\"Lin为必填字段\"},\"state\":0}",
+		},
+		map[string]string{
+			"lang":     "ja,en;q=0.8",
+			"expected": "{\"code\":402,\"msg\":{\"lin\":\"Linは必須フィールドです\"},\"state\":0}",
+		},
+		map[string]string{
+			"lang":     "de;q=0.9,en-US;q=0.8,ja;q=0.1",
+			"expected": "{\"code\":402,\"msg\":{\"lin\":\"Lin is a required field\"},\"state\":0}",
+		},
+	}
+	for _, ele := range eles {
+		rsp, err := grequests.Get(baseTestURL+"/router-locale", &grequests.RequestOptions{
+			Headers: map[string]string{"Accept-Language": ele["lang"]},
+		})
+		if err != nil {
+			t.Fatal("grequests.Get:", err)
+		}
+		assert.Equal(t, true, rsp.Ok)
+		assert.Equal(t, ele["expected"], rsp.String())
+		rsp.Close()
+	}
+
+	rsp, err := grequests.Get(baseTestURL+"/router-locale?lin=Tan", &grequests.RequestOptions{
+		Headers: map[string]string{"Accept-Language": "zh-Hant-TW"},
+	})
+	if err != nil {
+		t.Fatal("grequests.Get:", err)
+	}
+	assert.Equal(t, "locale:zh_tw", rsp.String())
+	rsp.Close()
+}
+
+func TestMultipleRouters(t *testing.T) {
+	type Params struct {
+		Name string `form:"name" binding:"required"`
+		Age  int    `form:"age" validate:"min=18"`
+	}
+	hd := NewInterface(
+		Interface{Path: "/info", Param: Params{}, Method: "GET"},
+		func(c *Context) {
+			c.GinContext.String(http.StatusOK, "ok")
+		},
+	)
+	engin := gin.New()
+	defaultValidator := binding.Validator
+	zh := NewRouter(WithRouter(engin.Group("/zh")), WithVaidatorV9("zh"))
+	en := NewRouter(WithRouter(engin.Group("/en")), WithVaidatorV9("en"))
+	v8 := NewRouter(WithRouter(engin.Group("/v8")))
+	tag := NewRouter(WithRouter(engin.Group("/tag")), WithVaidatorV9("en"), WithValidatorTagName("validate"))
+	zh.Add(hd)
+	en.Add(hd)
+	v8.Add(hd)
+	tag.Add(hd)
+	assert.Equal(t, defaultValidator, binding.Validator)
+
+	eles := []map[string]string{
+		map[string]string{
+			"url":      "/zh/info",
+			"expected": "{\"code\":402,\"msg\":{\"name\":\"Name为必填字段\"},\"state\":0}",
+		},
+		map[string]string{
+			"url":      "/en/info",
+			"expected": "{\"code\":402,\"msg\":{\"name\":\"Name is a required field\"},\"state\":0}",
+		},
+		map[string]string{
+			"url":      "/v8/info",
+			"expected": "{\"code\":402,\"msg\":{\"name\":\"param 'name' with value '' failed on the validation tag 'required'\"},\"state\":0}",
+		},
+		map[string]string{
+			"url":      "/tag/info?age=1",
+			"expected": "{\"code\":402,\"msg\":{\"age\":\"Age must be 18 or greater\"},\"state\":0}",
+		},
+	}
+	for _, ele := range eles {
+		w := httptest.NewRecorder()
+		engin.ServeHTTP(w, httptest.NewRequest("GET", ele["url"], nil))
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, ele["expected"], w.Body.String())
+	}
+}
+
+func TestValidatorV10(t *testing.T) {
+	type Params struct {
+		Name  string `form:"name" binding:"required" err-required:"name is required"`
+		Email string `form:"email" binding:"omitempty,email"`
+		Age   *int   `form:"age" binding:"exists"`
+	}
+	hd := NewInterface(
+		Interface{Path: "/info", Param: Params{}, Method: "GET"},
+		func(c *Context) {
+			c.GinContext.String(http.StatusOK, "ok")
+		},
+	)
+	engin := gin.New()
+	v8 := NewRouter(WithRouter(engin.Group("/v8")))
+	v10 := NewRouter(WithRouter(engin.Group("/v10")), WithValidatorV10("en"))
+	v8.Add(hd)
+	v10.Add(hd)
+
+	eles := []map[string]string{
+		map[string]string{
+			"url":      "/v10/info?email=abc&age=1",
+			"expected": "{\"code\":402,\"msg\":{\"email\":\"Email must be a valid email address\",\"name\":\"name is required\"},\"state\":0}",
+		},
+		map[string]string{
+			"url":      "/v10/info?name=Lin",
+			"expected": "{\"code\":402,\"msg\":{\"age\":\"Age is a required field\"},\"state\":0}",
+		},
+		map[string]string{
+			"url":      "/v10/info?name=Lin&age=1",
+			"expected": "ok",
+		},
+		map[string]string{
+			"url":      "/v8/info?name=Lin&age=1",
+			"expected": "ok",
+		},
+	}
+	for _, ele := range eles {
+		w := httptest.NewRecorder()
+		engin.ServeHTTP(w, httptest.NewRequest("GET", ele["url"], nil))
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, ele["expected"], w.Body.String())
+	}
+}
+
+func TestRegisterValidation(t *testing.T) {
+	type Params struct {
+		Sku   string `form:"sku" binding:"required,sku"`
+		Start int    `form:"start"`
+		End   int    `form:"end"`
+	}
+	hd := NewInterface(
+		Interface{Path: "/sku", Param: Params{}, Method: "GET"},
+		func(c *Context) {
+			c.GinContext.String(http.StatusOK, "ok")
+		},
+	)
+	engin := gin.New()
+	router := NewRouter(WithRouter(engin.Group("/v10")), WithValidatorV10("en"))
+	err := router.RegisterValidation("sku", func(fl validatorv10.FieldLevel) bool {
+		return strings.HasPrefix(fl.Field().String(), "SKU-")
+	}, map[string]string{
+		"en": "{0} must be a valid sku",
+		"zh": "{0}必须是有效的sku",
+	})
+	assert.Nil(t, err)
+	err = router.RegisterStructValidation(Params{}, func(sl validatorv10.StructLevel) {
+		p := sl.Current().Interface().(Params)
+		if p.End < p.Start {
+			sl.ReportError(p.End, "End", "End", "gtefield", "Start")
+		}
+	})
+	assert.Nil(t, err)
+	assert.NotNil(t, router.RegisterValidation("bad", func() bool { return true }, nil))
+	router.Add(hd)
+
+	eles := []map[string]string{
+		map[string]string{
+			"url":      "/v10/sku?sku=abc",
+			"lang":     "en",
+			"expected": "{\"code\":402,\"msg\":{\"sku\":\"Sku must be a valid sku\"},\"state\":0}",
+		},
+		map[string]string{
+			"url":      "/v10/sku?sku=abc",
+			"lang":     "zh-CN",
+			"expected": "{\"code\":402,\"msg\":{\"sku\":\"Sku必须是有效的sku\"},\"state\":0}",
+		},
+		map[string]string{
+			"url":      "/v10/sku?sku=abc",
+			"lang":     "fr",
+			"expected": "{\"code\":402,\"msg\":{\"sku\":\"Sku must be a valid sku\"},\"state\":0}",
+		},
+		map[string]string{
+			"url":      "/v10/sku?sku=SKU-1&start=2&end=1",
+			"lang":     "en",
+			"expected": "{\"code\":402,\"msg\":{\"end\":\"End must be greater than or equal to Start\"},\"state\":0}",
+		},
+		map[string]string{
+			"url":      "/v10/sku?sku=SKU-1&start=1&end=2",
+			"lang":     "en",
+			"expected": "ok",
+		},
+	}
+	for _, ele := range eles {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest("GET", ele["url"], nil)
+		req.Header.Set("Accept-Language", ele["lang"])
+		engin.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, ele["expected"], w.Body.String())
+	}
+}
+
+type nestedItem struct {
+	Sku string `json:"sku" binding:"required" err-required:"sku of the item is required"`
+	Qty int    `json:"qty" binding:"min=1"`
+}
+
+type nestedAddress struct {
+	Zip string `json:"zip" binding:"len=6"`
+}
+
+type nestedMeta struct {
+	Source string `json:"source" binding:"required"`
+}
+
+type nestedParams struct {
+	nestedMeta
+	Items   []nestedItem          `json:"items" binding:"required,dive"`
+	Address *nestedAddress        `json:"address" binding:"required"`
+	Extra   map[string]nestedItem `json:"extra" binding:"dive"`
+	Tags    []string              `json:"tags" binding:"dive,min=2"`
+}
+
+func TestNestedErrorPath(t *testing.T) {
+	hd := NewInterface(
+		Interface{Path: "/order", Param: nestedParams{}, Method: "POST"},
+		func(c *Context) {
+			c.GinContext.String(http.StatusOK, "ok")
+		},
+	)
+	engin := gin.New()
+	v8 := NewRouter(WithRouter(engin.Group("/v8")))
+	v9 := NewRouter(WithRouter(engin.Group("/v9")), WithVaidatorV9("en"))
+	v8.Add(hd)
+	v9.Add(hd)
+
+	body := `{"source":"web","items":[{"sku":"a","qty":1},{"sku":"b","qty":1},{"qty":0}],` +
+		`"address":{"zip":"123"},"extra":{"gift":{"qty":1}},"tags":["ok","x"]}`
+	expected := map[string]map[string]string{
+		"/v8/order": map[string]string{
+			"items[2].sku":    "sku of the item is required",
+			"items[2].qty":    "param 'items[2].qty' with value '0' failed on the validation tag 'min'",
+			"address.zip":     "param 'address.zip' with value '123' failed on the validation tag 'len'",
+			"extra[gift].sku": "sku of the item is required",
+			"tags[1]":         "param 'tags[1]' with value 'x' failed on the validation tag 'min'",
+		},
+		"/v9/order": map[string]string{
+			"items[2].sku":    "sku of the item is required",
+			"items[2].qty":    "Qty must be 1 or greater",
+			"address.zip":     "Zip must be 6 characters in length",
+			"extra[gift].sku": "sku of the item is required",
+			"tags[1]":         "Tags[1] must be at least 2 characters in length",
+		},
+	}
+	for url, msg := range expected {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest("POST", url, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		engin.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusOK, w.Code)
+		var rsp struct {
+			Code int               `json:"code"`
+			Msg  map[string]string `json:"msg"`
+		}
+		assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &rsp))
+		assert.Equal(t, 402, rsp.Code)
+		assert.Equal(t, msg, rsp.Msg, url)
+	}
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest("POST", "/v9/order", strings.NewReader(`{"items":[{"sku":"a","qty":1}],"address":{"zip":"123456"}}`))
+	req.Header.Set("Content-Type", "application/json")
+	engin.ServeHTTP(w, req)
+	assert.Equal(t, "{\"code\":402,\"msg\":{\"source\":\"Source is a required field\"},\"state\":0}", w.Body.String())
+}
+
+type bindNode struct {
+	Name   string    `json:"name"`
+	Parent *bindNode `json:"parent"`
+}
+
+type bindQueryNode struct {
+	ID     int `query:"id"`
+	Parent *bindQueryNode
+	Child  *bindNode
+}
+
+func TestBindRecursiveParam(t *testing.T) {
+	engin := gin.New()
+	router := NewRouter(WithRouter(engin.Group("/")))
+	router.Add(NewInterface(Interface{Path: "/node", Method: "POST", Param: bindNode{}}, func(c *Context) {
+		c.GinContext.JSON(http.StatusOK, c.Param)
+	}))
+	router.Add(NewInterface(Interface{Path: "/query-node", Method: "GET", Param: bindQueryNode{}}, func(c *Context) {
+		node := c.Param.(*bindQueryNode)
+		c.GinContext.String(http.StatusOK, "%d %v %v", node.ID, node.Parent == nil, node.Child == nil)
+	}))
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest("POST", "/node", strings.NewReader(`{"name":"a","parent":{"name":"b"}}`))
+	req.Header.Set("Content-Type", "application/json")
+	engin.ServeHTTP(w, req)
+	assert.Equal(t, "{\"name\":\"a\",\"parent\":{\"name\":\"b\",\"parent\":null}}", w.Body.String())
+
+	w = httptest.NewRecorder()
+	engin.ServeHTTP(w, httptest.NewRequest("GET", "/query-node?id=1", nil))
+	assert.Equal(t, "1 true true", w.Body.String())
+}
+
+func TestBindFormDefault(t *testing.T) {
+	type Paging struct {
+		Size int `form:"size,default=10"`
+	}
+	type Params struct {
+		Page   int `form:"page,default=3"`
+		Name   string
+		Tags   []string `form:"tags,default=a"`
+		Secret string   `form:"-"`
+		Paging
+	}
+	engin := gin.New()
+	router := NewRouter(WithRouter(engin.Group("/")))
+	router.Add(NewInterface(Interface{Path: "/x", Method: "GET", Param: Params{}}, func(c *Context) {
+		c.GinContext.JSON(http.StatusOK, c.Param)
+	}))
+
+	for url, expected := range map[string]string{
+		"/x?Name=bob":                     `{"Page":3,"Name":"bob","Tags":["a"],"Secret":"","Size":10}`,
+		"/x?page=5&tags=b&tags=c&size=20": `{"Page":5,"Name":"","Tags":["b","c"],"Secret":"","Size":20}`,
+		"/x?Secret=s&-=s&Page=7&Size=1":   `{"Page":3,"Name":"","Tags":["a"],"Secret":"","Size":10}`,
+	} {
+		w := httptest.NewRecorder()
+		engin.ServeHTTP(w, httptest.NewRequest("GET", url, nil))
+		assert.Equal(t, expected, w.Body.String(), url)
+	}
+}
+
+func TestBindErrors(t *testing.T) {
+	type Item struct {
+		Qty int `json:"qty"`
+	}
+	type Params struct {
+		Age    int             `json:"age" binding:"min=1"`
+		Name   string          `json:"name"`
+		Active bool            `json:"active"`
+		Items  []Item          `json:"items"`
+		Extra  map[string]Item `json:"extra"`
+		Page   int             `query:"page"`
+	}
+	var called bool
+	hd := NewInterface(
+		Interface{Path: "/bind", Param: Params{}, Method: "POST"},
+		func(c *Context) {
+			called = true
+			c.GinContext.String(http.StatusOK, "ok")
+		},
+	)
+	engin := gin.New()
+	router := NewRouter(WithRouter(engin.Group("/")))
+	router.Add(hd)
+
+	eles := []struct {
+		url    string
+		body   string
+		errors BindErrors
+	}{
+		{
+			url:  "/bind",
+			body: `{"age":"abc"}`,
+			errors: BindErrors{
+				{Field: "age", Source: "json", Expected: "int", Value: "abc"},
+			},
+		},
+		{
+			url:  "/bind?page=x",
+			body: `{"age":1.5,"name":"Lin"}`,
+			errors: BindErrors{
+				{Field: "age", Source: "json", Expected: "int", Value: "1.5"},
+				{Field: "page", Source: "query", Expected: "int", Value: "x"},
+			},
+		},
+		{
+			url:  "/bind",
+			body: `{"items":[{"qty":1},{"qty":"a\\\"b"}]}`,
+			errors: BindErrors{
+				{Field: "items[1].qty", Source: "json", Expected: "int", Value: `a\"b`},
+			},
+		},
+		{
+			url:  "/bind",
+			body: `{"extra":{"gift":{"qty":true}}}`,
+			errors: BindErrors{
+				{Field: "extra[gift].qty", Source: "json", Expected: "int", Value: "true"},
+			},
+		},
+		{
+			url:  "/bind",
+			body: `{"age":1,}`,
+			errors: BindErrors{
+				{Source: "json"},
+			},
+		},
+		{
+			url:  "/bind",
+			body: `{"age":`,
+			errors: BindErrors{
+				{Source: "json"},
+			},
+		},
+	}
+	for _, ele := range eles {
+		called = false
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest("POST", ele.url, strings.NewReader(ele.body))
+		req.Header.Set("Content-Type", "application/json")
+		engin.ServeHTTP(w, req)
+		assert.False(t, called, ele.body)
+		var rsp struct {
+			Code int `json:"code"`
+			Msg  []struct {
+				Field    string `json:"field"`
+				Source   string `json:"source"`
+				Expected string `json:"expected"`
+				Value    string `json:"value"`
+				Msg      string `json:"msg"`
+			} `json:"msg"`
+		}
+		assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &rsp), w.Body.String())
+		assert.Equal(t, 400, rsp.Code)
+		if assert.Equal(t, len(ele.errors), len(rsp.Msg), w.Body.String()) {
+			for i, e := range ele.errors {
+				assert.Equal(t, e.Field, rsp.Msg[i].Field)
+				assert.Equal(t, e.Source, rsp.Msg[i].Source)
+				assert.Equal(t, e.Expected, rsp.Msg[i].Expected)
+				assert.Equal(t, e.Value, rsp.Msg[i].Value)
+				assert.NotEmpty(t, rsp.Msg[i].Msg)
+			}
+		}
+	}
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest("POST", "/bind", strings.NewReader(`{"age":0}`))
+	req.Header.Set("Content-Type", "application/json")
+	engin.ServeHTTP(w, req)
+	assert.Equal(t, "{\"code\":402,\"msg\":{\"age\":\"param 'age' with value '0' failed on the validation tag 'min'\"},\"state\":0}", w.Body.String())
+}
+
+// panicMessage - message of the error f panics with.
+func panicMessage(f func()) (msg string) {
+	defer func() {
+		if err, ok := recover().(error); ok {
+			msg = err.Error()
+		}
+	}()
+	f()
+	return
+}
+
+func TestRouterSteps(t *testing.T) {
+	var (
+		mu    sync.Mutex
+		trace []string
+	)
+	step := func(name string, delay time.Duration, err error) StepFunc {
+		return func(ctx context.Context, c *Context) error {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(delay):
+			}
+			if err != nil {
+				return err
+			}
+			mu.Lock()
+			trace = append(trace, name)
+			mu.Unlock()
+			return nil
+		}
+	}
+	hd := func(path string, steps ...Step) Interface {
+		return NewInterface(
+			Interface{Path: path, Method: "GET", Steps: steps},
+			func(c *Context) {
+				c.GinContext.String(http.StatusOK, "ok")
+			},
+		)
+	}
+	engin := gin.New()
+	router := NewRouter(WithRouter(engin.Group("/")))
+	router.Add(hd("/dag",
+		Step{Name: "user", Handle: step("user", 100*time.Millisecond, nil)},
+		Step{Name: "orders", After: []string{"user"}, Handle: step("orders", 10*time.Millisecond, nil)},
+		Step{Name: "config", Handle: step("config", 150*time.Millisecond, nil)},
+		Step{Name: "render", After: []string{"orders", "config"}, Handle: step("render", 0, nil)},
+	))
+	router.Add(hd("/dag-failure",
+		Step{Name: "user", Handle: step("user", 10*time.Millisecond, errors.New("user err"))},
+		Step{Name: "orders", After: []string{"user"}, Handle: step("orders", 0, nil)},
+		Step{Name: "config", Handle: step("config", time.Second, nil)},
+	))
+
+	begin := time.Now()
+	w := httptest.NewRecorder()
+	engin.ServeHTTP(w, httptest.NewRequest("GET", "/dag", nil))
+	// user and orders run along with config.
+	assert.True(t, time.Since(begin) < 250*time.Millisecond)
+	assert.Equal(t, "ok", w.Body.String())
+	assert.Equal(t, []string{"user", "orders", "config", "render"}, trace)
+
+	trace = nil
+	begin = time.Now()
+	w = httptest.NewRecorder()
+	engin.ServeHTTP(w, httptest.NewRequest("GET", "/dag-failure", nil))
+	assert.True(t, time.Since(begin) < 500*time.Millisecond)
+	assert.Equal(t, "{\"code\":402,\"msg\":\"user err\",\"state\":0}", w.Body.String())
+	mu.Lock()
+	assert.Empty(t, trace)
+	mu.Unlock()
+
+	assert.Equal(t, "the interface [GET /cycle]: the steps a,b,c form a cycle", panicMessage(func() {
+		router.Add(hd("/cycle",
+			Step{Name: "a", After: []string{"c"}, Handle: step("a", 0, nil)},
+			Step{Name: "b", After: []string{"a"}, Handle: step("b", 0, nil)},
+			Step{Name: "c", After: []string{"b"}, Handle: step("c", 0, nil)},
+		))
+	}))
+	assert.Equal(t, "the interface [GET /unknown]: the step a depends on the unknown step b", panicMessage(func() {
+		router.Add(hd("/unknown", Step{Name: "a", After: []string{"b"}, Handle: step("a", 0, nil)}))
+	}))
+	assert.Equal(t, "the interface [GET /duplicated]: the step async-0 is duplicated", panicMessage(func() {
+		inter := hd("/duplicated", Step{Name: "async-0", Handle: step("a", 0, nil)})
+		inter.AsyncHandleFunc = ErrHandleFuncChain{func(*Context) error { return nil }}
+		router.Add(inter)
+	}))
+}
+
+func TestContextStore(t *testing.T) {
+	type user struct {
+		Name string
+	}
+	var steps []Step
+	var after []string
+	for i := 0; i < 20; i++ {
+		i := i
+		name := fmt.Sprintf("score-%d", i)
+		steps = append(steps, Step{Name: name, Handle: func(ctx context.Context, c *Context) error {
+			Set(c, name, i)
+			return nil
+		}})
+		after = append(after, name)
+	}
+	steps = append(steps,
+		Step{Name: "user", Handle: func(ctx context.Context, c *Context) error {
+			Set(c, "user", &user{Name: "Tan"})
+			return nil
+		}},
+		Step{Name: "total", After: after, Handle: func(ctx context.Context, c *Context) error {
+			total := 0
+			for _, name := range after {
+				v, ok := Get[int](c, name)
+				if !ok {
+					return fmt.Errorf("%s is not set", name)
+				}
+				total += v
+			}
+			Set(c, "total", total)
+			return nil
+		}},
+	)
+	hd := NewInterface(
+		Interface{Path: "/store", Method: "GET", Steps: steps},
+		func(c *Context) {
+			u, _ := Get[*user](c, "user")
+			total, _ := Get[int](c, "total")
+			_, ok := Get[string](c, "total")
+			c.GinContext.String(http.StatusOK, fmt.Sprintf("%s:%d:%t", u.Name, total, ok))
+		},
+	)
+	engin := gin.New()
+	router := NewRouter(WithRouter(engin.Group("/")))
+	router.Add(hd)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			w := httptest.NewRecorder()
+			engin.ServeHTTP(w, httptest.NewRequest("GET", "/store", nil))
+			assert.Equal(t, "Tan:190:false", w.Body.String())
+		}()
+	}
+	wg.Wait()
+}
+
+func TestStepTimeout(t *testing.T) {
+	sleep := func(d time.Duration) StepFunc {
+		return func(ctx context.Context, c *Context) error {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(d):
+			}
+			return nil
+		}
+	}
+	hd := func(inter Interface) Interface {
+		inter.Method = "GET"
+		return NewInterface(inter, func(c *Context) {
+			c.GinContext.String(http.StatusOK, "ok")
+		})
+	}
+	engin := gin.New()
+	var timeoutErr *TimeoutError
+	router := NewRouter(
+		WithRouter(engin.Group("/")),
+		WithStepTimeout(time.Second),
+		WithErrHandle(func(c *Context, err interface{}) {
+			if e, ok := err.(error); ok && errors.As(e, &timeoutErr) && errors.Is(e, context.DeadlineExceeded) {
+				c.GinContext.String(http.StatusGatewayTimeout, e.Error())
+				return
+			}
+			c.GinContext.String(http.StatusInternalServerError, fmt.Sprint(err))
+		}),
+	)
+	router.Add(hd(Interface{Path: "/step-timeout", Steps: []Step{
+		{Name: "fast", Handle: sleep(10 * time.Millisecond)},
+		{Name: "slow", Handle: sleep(time.Second), Timeout: 50 * time.Millisecond},
+	}}))
+	router.Add(hd(Interface{Path: "/interface-timeout", StepTimeout: 50 * time.Millisecond, Steps: []Step{
+		{Name: "slow", Handle: sleep(time.Second)},
+	}}))
+	router.Add(hd(Interface{Path: "/budget", StepBudget: 100 * time.Millisecond, Steps: []Step{
+		{Name: "first", Handle: sleep(60 * time.Millisecond)},
+		{Name: "second", After: []string{"first"}, Handle: sleep(60 * time.Millisecond)},
+	}}))
+	router.Add(hd(Interface{Path: "/ignore-ctx", StepTimeout: 50 * time.Millisecond, StepFailure: StepFailureDetach, SyncHandleFunc: ErrHandleFuncChain{
+		func(c *Context) error {
+			time.Sleep(300 * time.Millisecond)
+			return nil
+		},
+	}}))
+	router.Add(hd(Interface{Path: "/legacy-timeout", StepTimeout: 50 * time.Millisecond, AsyncHandleFunc: ErrHandleFuncChain{
+		func(c *Context) error {
+			time.Sleep(300 * time.Millisecond)
+			return nil
+		},
+		func(c *Context) error { return nil },
+	}}))
+	router.Add(hd(Interface{Path: "/client-context", StepBudget: 50 * time.Millisecond, AsyncHandleFunc: ErrHandleFuncChain{
+		func(c *Context) error {
+			<-c.ClientContext.Done()
+			return c.ClientContext.Err()
+		},
+	}}))
+	router.Add(hd(Interface{Path: "/in-time", StepBudget: time.Second, Steps: []Step{
+		{Name: "fast", Handle: sleep(10 * time.Millisecond)},
+	}}))
+
+	eles := []struct {
+		path     string
+		code     int
+		expected string
+	}{
+		{"/step-timeout", http.StatusGatewayTimeout, "the step slow times out after 50ms"},
+		{"/interface-timeout", http.StatusGatewayTimeout, "the step slow times out after 50ms"},
+		{"/budget", http.StatusGatewayTimeout, "the steps exceed the budget 100ms at the step second"},
+		{"/ignore-ctx", http.StatusGatewayTimeout, "the step sync-0 times out after 50ms"},
+		{"/legacy-timeout", http.StatusGatewayTimeout, "the step async-0 times out after 50ms"},
+		{"/client-context", http.StatusGatewayTimeout, "the steps exceed the budget 50ms at the step async-0"},
+		{"/in-time", http.StatusOK, "ok"},
+	}
+	for _, ele := range eles {
+		begin := time.Now()
+		w := httptest.NewRecorder()
+		engin.ServeHTTP(w, httptest.NewRequest("GET", ele.path, nil))
+		assert.True(t, time.Since(begin) < 200*time.Millisecond, ele.path)
+		assert.Equal(t, ele.code, w.Code, ele.path)
+		assert.Equal(t, ele.expected, w.Body.String(), ele.path)
+	}
+
+	engin = gin.New()
+	router = NewRouter(WithRouter(engin.Group("/")))
+	router.Add(hd(Interface{Path: "/default", StepTimeout: 10 * time.Millisecond, Steps: []Step{
+		{Name: "slow", Handle: sleep(time.Second)},
+	}}))
+	w := httptest.NewRecorder()
+	engin.ServeHTTP(w, httptest.NewRequest("GET", "/default", nil))
+	assert.Equal(t, "{\"code\":504,\"msg\":\"the step slow times out after 10ms\",\"state\":0}", w.Body.String())
+
+	type clientKey struct{}
+	clientContext := context.WithValue(context.Background(), clientKey{}, "client")
+	engin = gin.New()
+	router = NewRouter(WithRouter(engin.Group("/")), WithClientContext(clientContext))
+	router.Add(NewInterface(Interface{Path: "/client", Method: "GET", Steps: []Step{
+		{Name: "load", Handle: func(ctx context.Context, c *Context) error {
+			Set(c, "step", ctx.Value(clientKey{}))
+			return nil
+		}},
+	}}, func(c *Context) {
+		step, _ := Get[string](c, "step")
+		c.GinContext.String(http.StatusOK, "%s %v", step, c.ClientContext == clientContext)
+	}))
+	w = httptest.NewRecorder()
+	engin.ServeHTTP(w, httptest.NewRequest("GET", "/client", nil))
+	assert.Equal(t, "client true", w.Body.String())
+}
+
+// waitGoroutines - wait until the goroutines drop to n,returns the last count.
+func waitGoroutines(n int, timeout time.Duration) int {
+	deadline := time.Now().Add(timeout)
+	for {
+		count := runtime.NumGoroutine()
+		if count <= n || time.Now().After(deadline) {
+			return count
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestStepFailure(t *testing.T) {
+	var running int32
+	step := func(d time.Duration, err error, ignoreCtx bool) StepFunc {
+		return func(ctx context.Context, c *Context) error {
+			atomic.AddInt32(&running, 1)
+			defer atomic.AddInt32(&running, -1)
+			if ignoreCtx {
+				time.Sleep(d)
+				return err
+			}
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(d):
+			}
+			return err
+		}
+	}
+	hd := func(path string, failure StepFailure, steps ...Step) Interface {
+		return NewInterface(
+			Interface{Path: path, Method: "GET", Steps: steps, StepFailure: failure},
+			func(c *Context) {
+				c.GinContext.String(http.StatusOK, "ok")
+			},
+		)
+	}
+	engin := gin.New()
+	router := NewRouter(
+		WithRouter(engin.Group("/")),
+		WithErrHandle(func(c *Context, err interface{}) {
+			var errs StepErrors
+			if e, ok := err.(error); ok && errors.As(e, &errs) {
+				c.GinContext.String(http.StatusOK, fmt.Sprintf("%d:%s", len(errs), e.Error()))
+				return
+			}
+			c.GinContext.String(http.StatusOK, fmt.Sprint(err))
+		}),
+	)
+	router.Add(hd("/wait", 0,
+		Step{Name: "a", Handle: step(10*time.Millisecond, errors.New("a err"), false)},
+		Step{Name: "b", Handle: step(time.Second, nil, false)},
+		Step{Name: "c", Handle: step(100*time.Millisecond, nil, true)},
+		Step{Name: "d", After: []string{"b"}, Handle: step(0, nil, false)},
+	))
+	router.Add(hd("/detach", StepFailureDetach,
+		Step{Name: "a", Handle: step(10*time.Millisecond, errors.New("a err"), false)},
+		Step{Name: "c", Handle: step(100*time.Millisecond, nil, true)},
+	))
+	router.Add(hd("/collect", StepFailureCollect,
+		Step{Name: "a", Handle: step(10*time.Millisecond, errors.New("a err"), false)},
+		Step{Name: "b", Handle: step(30*time.Millisecond, errors.New("b err"), false)},
+		Step{Name: "c", Handle: step(50*time.Millisecond, nil, false)},
+		Step{Name: "d", After: []string{"a"}, Handle: step(0, errors.New("d err"), false)},
+		Step{Name: "e", After: []string{"c"}, Handle: step(0, errors.New("e err"), false)},
+	))
+	router.Add(hd("/success", 0,
+		Step{Name: "a", Handle: step(10*time.Millisecond, nil, false)},
+		Step{Name: "b", Handle: step(20*time.Millisecond, nil, false)},
+	))
+
+	// the goroutines left by the former tests exit first.
+	time.Sleep(300 * time.Millisecond)
+	base := runtime.NumGoroutine()
+	for i := 0; i < 5; i++ {
+		for _, path := range []string{"/success", "/wait", "/collect"} {
+			w := httptest.NewRecorder()
+			engin.ServeHTTP(w, httptest.NewRequest("GET", path, nil))
+			// no step runs after the response.
+			assert.Equal(t, int32(0), atomic.LoadInt32(&running), path)
+		}
+	}
+	assert.True(t, waitGoroutines(base, 100*time.Millisecond) <= base)
+
+	w := httptest.NewRecorder()
+	engin.ServeHTTP(w, httptest.NewRequest("GET", "/wait", nil))
+	assert.Equal(t, "a err", w.Body.String())
+	w = httptest.NewRecorder()
+	engin.ServeHTTP(w, httptest.NewRequest("GET", "/collect", nil))
+	assert.Equal(t, "3:step a: a err; step b: b err; step e: e err", w.Body.String())
+
+	begin := time.Now()
+	w = httptest.NewRecorder()
+	engin.ServeHTTP(w, httptest.NewRequest("GET", "/detach", nil))
+	assert.True(t, time.Since(begin) < 50*time.Millisecond)
+	assert.Equal(t, "a err", w.Body.String())
+	// the detached step keeps running until it returns.
+	assert.Equal(t, int32(1), atomic.LoadInt32(&running))
+	for i := 0; i < 100 && atomic.LoadInt32(&running) > 0; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	assert.Equal(t, int32(0), atomic.LoadInt32(&running))
+	assert.True(t, waitGoroutines(base, 100*time.Millisecond) <= base)
+}
+
+func TestStepDetachRequest(t *testing.T) {
+	read := make(chan string, 1)
+	engin := gin.New()
+	router := NewRouter(
+		WithRouter(engin.Group("/")),
+		WithStepFailure(StepFailureDetach),
+		WithMiddlerware(func(c *gin.Context) {
+			c.Set("user", c.Query("id"))
+		}),
+	)
+	router.Add(NewInterface(Interface{Path: "/detach", Method: "GET", Steps: []Step{
+		{Name: "fail", Handle: func(ctx context.Context, c *Context) error {
+			if c.GinContext.Query("id") == "1" {
+				return errors.New("fail")
+			}
+			return nil
+		}},
+		{Name: "read", Handle: func(ctx context.Context, c *Context) error {
+			if c.GinContext.Query("id") == "1" {
+				// read the request after the response.
+				time.Sleep(50 * time.Millisecond)
+				read <- c.GinContext.Query("id") + " " + c.GinContext.GetString("user")
+			}
+			return nil
+		}},
+	}}, func(c *Context) {
+		c.GinContext.String(http.StatusOK, "ok")
+	}))
+
+	w := httptest.NewRecorder()
+	engin.ServeHTTP(w, httptest.NewRequest("GET", "/detach?id=1", nil))
+	assert.Equal(t, "{\"code\":402,\"msg\":\"fail\",\"state\":0}", w.Body.String())
+	// the later requests reuse the gin context of the former one.
+	for i := 2; i < 10; i++ {
+		w := httptest.NewRecorder()
+		engin.ServeHTTP(w, httptest.NewRequest("GET", fmt.Sprintf("/detach?id=%d", i), nil))
+		assert.Equal(t, "ok", w.Body.String())
+	}
+	assert.Equal(t, "1 1", <-read)
+}
+
+func TestStepGinContext(t *testing.T) {
+	hd := func(inter Interface) Interface {
+		inter.Method = "GET"
+		return NewInterface(inter, func(c *Context) {
+			// the response written by the middleware.
+			if c.GinContext.Writer.Written() {
+				return
+			}
+			c.GinContext.String(http.StatusOK, "user=%v", c.GinContext.Value("user"))
+		})
+	}
+	done := make(chan struct{}, 2)
+	engin := gin.New()
+	router := NewRouter(WithRouter(engin.Group("/")), WithStepTimeout(time.Second))
+	router.Add(hd(Interface{Path: "/set", SyncHandleFunc: ErrHandleFuncChain{
+		func(c *Context) error {
+			c.GinContext.Set("user", "bob")
+			return nil
+		},
+	}}))
+	router.Add(hd(Interface{Path: "/forbid", AsyncHandleFunc: ErrHandleFuncChain{
+		func(c *Context) error {
+			c.GinContext.AbortWithStatusJSON(http.StatusForbidden, gin.H{"msg": "forbidden"})
+			return nil
+		},
+	}}))
+	router.Add(hd(Interface{Path: "/left", StepTimeout: 20 * time.Millisecond, AsyncHandleFunc: ErrHandleFuncChain{
+		func(c *Context) error {
+			time.Sleep(50 * time.Millisecond)
+			defer func() { done <- struct{}{} }()
+			c.ErrCode = http.StatusInternalServerError
+			c.GinContext.Set("user", "late")
+			c.GinContext.String(http.StatusOK, "late")
+			return errors.New("late")
+		},
+	}}))
+
+	detach := NewRouter(WithRouter(engin.Group("/detach")), WithStepFailure(StepFailureDetach))
+	detach.Add(hd(Interface{Path: "/ok", Steps: []Step{
+		{Name: "user", Handle: func(ctx context.Context, c *Context) error {
+			c.GinContext.Set("user", "tom")
+			return nil
+		}},
+		{Name: "header", After: []string{"user"}, Handle: func(ctx context.Context, c *Context) error {
+			c.GinContext.Header("X-User", c.GinContext.GetString("user"))
+			return nil
+		}},
+	}}))
+	detach.Add(hd(Interface{Path: "/fail", Steps: []Step{
+		{Name: "fail", Handle: func(ctx context.Context, c *Context) error {
+			c.ErrCode = http.StatusForbidden
+			return errors.New("fail")
+		}},
+		{Name: "slow", Handle: func(ctx context.Context, c *Context) error {
+			time.Sleep(50 * time.Millisecond)
+			defer func() { done <- struct{}{} }()
+			c.ErrCode = http.StatusInternalServerError
+			c.GinContext.Header("X-Late", "1")
+			return nil
+		}},
+	}}))
+
+	eles := []struct {
+		path     string
+		code     int
+		expected string
+		header   string
+	}{
+		{"/set", http.StatusOK, "user=bob", ""},
+		{"/forbid", http.StatusForbidden, "{\"msg\":\"forbidden\"}", ""},
+		{"/left", http.StatusOK, "{\"code\":504,\"msg\":\"the step async-0 times out after 20ms\",\"state\":0}", ""},
+		{"/detach/ok", http.StatusOK, "user=tom", "tom"},
+		{"/detach/fail", http.StatusOK, "{\"code\":403,\"msg\":\"fail\",\"state\":0}", ""},
+	}
+	for _, ele := range eles {
+		w := httptest.NewRecorder()
+		engin.ServeHTTP(w, httptest.NewRequest("GET", ele.path, nil))
+		assert.Equal(t, ele.code, w.Code, ele.path)
+		assert.Equal(t, ele.expected, w.Body.String(), ele.path)
+		assert.Equal(t, ele.header, w.Header().Get("X-User"), ele.path)
+		// the steps left behind write nothing after the response.
+		if ele.path == "/left" || ele.path == "/detach/fail" {
+			<-done
+			assert.Equal(t, ele.expected, w.Body.String(), ele.path)
+			assert.Equal(t, "", w.Header().Get("X-Late"), ele.path)
+		}
+	}
+}
+
+func TestStepPanic(t *testing.T) {
+	var (
+		mu       sync.Mutex
+		reported []*PanicError
+	)
+	hd := func(path string, inter Interface) Interface {
+		inter.Path, inter.Method = path, "GET"
+		return NewInterface(inter, func(c *Context) {
+			c.GinContext.String(http.StatusOK, "ok")
+		})
+	}
+	engin := gin.New()
+	router := NewRouter(
+		WithRouter(engin.Group("/")),
+		WithPanicReporter(func(c *Context, err *PanicError) {
+			mu.Lock()
+			reported = append(reported, err)
+			mu.Unlock()
+		}),
+	)
+	router.Add(hd("/async", Interface{AsyncHandleFunc: ErrHandleFuncChain{
+		func(c *Context) error { return nil },
+		func(c *Context) error { panic("async boom") },
+	}}))
+	router.Add(hd("/sync", Interface{SyncHandleFunc: ErrHandleFuncChain{
+		func(c *Context) error {
+			var m map[string]int
+			m["a"] = 1
+			return nil
+		},
+	}}))
+	router.Add(hd("/timeout", Interface{StepTimeout: time.Second, Steps: []Step{
+		{Name: "load", Handle: func(ctx context.Context, c *Context) error { panic(errors.New("load boom")) }},
+	}}))
+	router.Add(hd("/fallback", Interface{Steps: []Step{
+		{Name: "user", Handle: func(ctx context.Context, c *Context) error { return nil }},
+		{
+			Name:     "score",
+			Handle:   func(ctx context.Context, c *Context) error { return errors.New("score unavailable") },
+			Fallback: func(c *Context, err error) { panic("fallback boom") },
+		},
+	}}))
+
+	eles := []map[string]string{
+		map[string]string{
+			"path":     "/async",
+			"expected": "{\"code\":500,\"msg\":\"the step async-1 panics: async boom\",\"state\":0}",
+		},
+		map[string]string{
+			"path":     "/sync",
+			"expected": "{\"code\":500,\"msg\":\"the step sync-0 panics: assignment to entry in nil map\",\"state\":0}",
+		},
+		map[string]string{
+			"path":     "/timeout",
+			"expected": "{\"code\":500,\"msg\":\"the step load panics: load boom\",\"state\":0}",
+		},
+		map[string]string{
+			"path":     "/fallback",
+			"expected": "{\"code\":500,\"msg\":\"the step score panics: fallback boom\",\"state\":0}",
+		},
+	}
+	for _, ele := range eles {
+		w := httptest.NewRecorder()
+		assert.NotPanics(t, func() {
+			engin.ServeHTTP(w, httptest.NewRequest("GET", ele["path"], nil))
+		})
+		assert.Equal(t, ele["expected"], w.Body.String())
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if assert.Len(t, reported, 4) {
+		assert.Equal(t, "async boom", reported[0].Value)
+		assert.Contains(t, string(reported[0].Stack), "router_test.go")
+		var err error = reported[2]
+		assert.EqualError(t, errors.Unwrap(err), "load boom")
+		assert.Equal(t, "fallback boom", reported[3].Value)
+	}
+}
+
+func TestStepPolicy(t *testing.T) {
+	flaky := func(key string, failures int32, attempts *int32) StepFunc {
+		return func(ctx context.Context, c *Context) error {
+			if atomic.AddInt32(attempts, 1) <= failures {
+				return fmt.Errorf("%s unavailable", key)
+			}
+			Set(c, key, key+" loaded")
+			return nil
+		}
+	}
+	var recommend, rates, stock, profile int32
+	hd := func(path string, steps ...Step) Interface {
+		return NewInterface(
+			Interface{Path: path, Method: "GET", Steps: steps},
+			func(c *Context) {
+				var missing []string
+				for _, e := range c.FailedSteps() {
+					missing = append(missing, e.Error())
+				}
+				sort.Strings(missing)
+				r, _ := Get[string](c, "rates")
+				s, _ := Get[string](c, "stock")
+				p, _ := Get[string](c, "profile")
+				c.GinContext.JSON(http.StatusOK, gin.H{
+					"missing": missing,
+					"rates":   r,
+					"stock":   s,
+					"profile": p,
+				})
+			},
+		)
+	}
+	engin := gin.New()
+	router := NewRouter(WithRouter(engin.Group("/")))
+	router.Add(hd("/aggregate",
+		Step{Name: "recommend", Optional: true, Handle: flaky("recommend", 100, &recommend)},
+		Step{Name: "rates", Fallback: FallbackValue("rates", "default rates"), Handle: flaky("rates", 100, &rates)},
+		Step{Name: "stock", Retries: 3, Backoff: time.Millisecond, Handle: flaky("stock", 2, &stock)},
+		Step{Name: "profile", After: []string{"recommend"}, Handle: flaky("profile", 0, &profile)},
+	))
+	router.Add(hd("/required",
+		Step{Name: "stock", Retries: 2, Backoff: time.Millisecond, Handle: flaky("stock", 100, &stock)},
+	))
+
+	w := httptest.NewRecorder()
+	engin.ServeHTTP(w, httptest.NewRequest("GET", "/aggregate", nil))
+	assert.Equal(t, "{\"missing\":[\"step rates: rates unavailable\",\"step recommend: recommend unavailable\"],"+
+		"\"profile\":\"profile loaded\",\"rates\":\"default rates\",\"stock\":\"stock loaded\"}", w.Body.String())
+	assert.Equal(t, int32(1), atomic.LoadInt32(&recommend))
+	assert.Equal(t, int32(3), atomic.LoadInt32(&stock))
+
+	atomic.StoreInt32(&stock, 0)
+	w = httptest.NewRecorder()
+	engin.ServeHTTP(w, httptest.NewRequest("GET", "/required", nil))
+	assert.Equal(t, "{\"code\":402,\"msg\":\"stock unavailable\",\"state\":0}", w.Body.String())
+	assert.Equal(t, int32(3), atomic.LoadInt32(&stock))
+}
+
+func TestStepConcurrency(t *testing.T) {
+	var running, maxRunning int32
+	step := func(ctx context.Context, c *Context) error {
+		n := atomic.AddInt32(&running, 1)
+		defer atomic.AddInt32(&running, -1)
+		for {
+			m := atomic.LoadInt32(&maxRunning)
+			if n <= m || atomic.CompareAndSwapInt32(&maxRunning, m, n) {
+				break
+			}
+		}
+		time.Sleep(30 * time.Millisecond)
+		return nil
+	}
+	steps := func(n int) []Step {
+		var steps []Step
+		for i := 0; i < n; i++ {
+			steps = append(steps, Step{Name: fmt.Sprintf("step-%d", i), Handle: step})
+		}
+		return steps
+	}
+	hd := func(inter Interface) Interface {
+		inter.Method = "GET"
+		return NewInterface(inter, func(c *Context) {
+			c.GinContext.String(http.StatusOK, "ok")
+		})
+	}
+	serve := func(engin *gin.Engine, path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		engin.ServeHTTP(w, httptest.NewRequest("GET", path, nil))
+		return w
+	}
+
+	// the limit of the router is shared by the requests.
+	engin := gin.New()
+	router := NewRouter(WithRouter(engin.Group("/")), WithStepConcurrency(2))
+	router.Add(hd(Interface{Path: "/wait", Steps: steps(4)}))
+	var wg sync.WaitGroup
+	for i := 0; i < 3; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			assert.Equal(t, "ok", serve(engin, "/wait").Body.String())
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, int32(2), atomic.LoadInt32(&maxRunning))
+	metrics := router.StepMetrics()
+	assert.Equal(t, int64(0), metrics.InFlight)
+	assert.Equal(t, int64(0), metrics.Waiting)
+	assert.True(t, metrics.Waited >= 2)
+	assert.True(t, metrics.WaitTime > 0)
+
+	engin = gin.New()
+	router = NewRouter(WithRouter(engin.Group("/")))
+	router.Add(hd(Interface{Path: "/reject", Steps: steps(2), StepConcurrency: 1, StepSaturation: SaturationReject}))
+	router.Add(hd(Interface{Path: "/inline", Steps: steps(3), StepConcurrency: 1, StepSaturation: SaturationInline}))
+	blocked, unblock := make(chan struct{}), make(chan struct{})
+	router.Add(hd(Interface{Path: "/single", StepConcurrency: 1, StepSaturation: SaturationReject, AsyncHandleFunc: ErrHandleFuncChain{
+		func(c *Context) error {
+			if c.GinContext.Query("block") != "" {
+				close(blocked)
+				<-unblock
+			}
+			return nil
+		},
+	}}))
+	// the request waits for the slot held by its own step.
+	assert.Equal(t, "ok", serve(engin, "/reject").Body.String())
+	assert.Equal(t, "ok", serve(engin, "/inline").Body.String())
+	// the only step holds the slot as well,which rejects the other requests.
+	done := make(chan string)
+	go func() {
+		done <- serve(engin, "/single?block=1").Body.String()
+	}()
+	<-blocked
+	assert.Equal(t, "{\"code\":503,\"msg\":\"the step async-0 is rejected as the concurrent steps reach the limit 1\",\"state\":0}",
+		serve(engin, "/single").Body.String())
+	assert.Equal(t, int64(1), router.StepMetrics().InFlight)
+	close(unblock)
+	assert.Equal(t, "ok", <-done)
+	assert.Equal(t, "ok", serve(engin, "/single").Body.String())
+	metrics = router.StepMetrics()
+	assert.Equal(t, int64(1), metrics.Rejected)
+	assert.True(t, metrics.Waited >= 1)
+	// step-0 may have released its slot after step-1 runs inline.
+	assert.True(t, metrics.Inlined >= 1)
+	assert.Equal(t, int64(0), metrics.InFlight)
+}
+
+func TestResponseEncoder(t *testing.T) {
+	type Params struct {
+		ID int `form:"id" binding:"required"`
+	}
+	result := NewResultInterface(
+		Interface{Path: "/result", Param: Params{}, Method: "GET"},
+		func(c *Context) (interface{}, error) {
+			p := c.Param.(*Params)
+			if p.ID == 404 {
+				return nil, errors.New("not found")
+			}
+			return gin.H{"id": p.ID}, nil
+		},
+	)
+	typed := NewTypedInterface(
+		TypedInterface[Params, typedResponse]{Path: "/typed", Method: "GET"},
+		func(c *Context, p *Params) (typedResponse, error) {
+			return typedResponse{Age: p.ID}, nil
+		},
+	)
+	newEngine := func(options ...Option) *gin.Engine {
+		engin := gin.New()
+		router := NewRouter(append([]Option{WithRouter(engin.Group("/"))}, options...)...)
+		router.Add(result)
+		router.Add(typed)
+		return engin
+	}
+	custom := func(c *Context, rsp interface{}, err error) {
+		if err != nil {
+			c.GinContext.JSON(http.StatusNotFound, gin.H{"ok": false, "error": err.Error()})
+			return
+		}
+		c.GinContext.JSON(http.StatusOK, gin.H{"ok": true, "result": rsp})
+	}
+
+	eles := []struct {
+		engin    *gin.Engine
+		url      string
+		code     int
+		expected string
+	}{
+		{newEngine(), "/result?id=1", 200, "{\"id\":1}"},
+		{newEngine(), "/result?id=404", 200, "{\"code\":402,\"msg\":\"not found\",\"state\":0}"},
+		{newEngine(WithResponseEncoder(EnvelopeResponseEncoder)), "/result?id=1", 200, "{\"code\":0,\"data\":{\"id\":1},\"state\":1}"},
+		{newEngine(WithResponseEncoder(EnvelopeResponseEncoder)), "/typed?id=2", 200, "{\"code\":0,\"data\":{\"greeting\":\"\",\"age\":2},\"state\":1}"},
+		{newEngine(WithResponseEncoder(EnvelopeResponseEncoder)), "/result", 200, "{\"code\":402,\"msg\":{\"id\":\"param 'id' with value '0' failed on the validation tag 'required'\"},\"state\":0}"},
+		{newEngine(WithResponseEncoder(custom)), "/result?id=404", 404, "{\"error\":\"not found\",\"ok\":false}"},
+		{newEngine(WithResponseEncoder(custom)), "/typed?id=3", 200, "{\"ok\":true,\"result\":{\"greeting\":\"\",\"age\":3}}"},
+	}
+	for _, ele := range eles {
+		w := httptest.NewRecorder()
+		ele.engin.ServeHTTP(w, httptest.NewRequest("GET", ele.url, nil))
+		assert.Equal(t, ele.code, w.Code, ele.url)
+		assert.Equal(t, ele.expected, w.Body.String(), ele.url)
+	}
+}
+
+func TestProblemDetails(t *testing.T) {
+	type Params struct {
+		ID   int    `form:"id" binding:"required"`
+		Name string `form:"name" binding:"required" err-required:"name is required"`
+	}
+	engin := gin.New()
+	router := NewRouter(WithRouter(engin.Group("/")), WithProblemDetails())
+	router.Add(NewResultInterface(
+		Interface{Path: "/problem", Param: Params{}, Method: "GET"},
+		func(c *Context) (interface{}, error) {
+			c.ErrCode = 1001
+			return nil, errors.New("score service is down")
+		},
+	))
+
+	eles := []map[string]interface{}{
+		map[string]interface{}{
+			"url":  "/problem?id=abc",
+			"code": http.StatusBadRequest,
+			"expected": "{\"type\":\"about:blank\",\"title\":\"Bad Request\",\"status\":400," +
+				"\"detail\":\"the request params failed to be decoded\",\"instance\":\"/problem\"," +
+				"\"errors\":[{\"field\":\"id\",\"source\":\"form\",\"expected\":\"int\",\"value\":\"abc\"," +
+				"\"message\":\"param 'id' with value 'abc' is not a valid int\"}]}",
+		},
+		map[string]interface{}{
+			"url":  "/problem",
+			"code": http.StatusUnprocessableEntity,
+			"expected": "{\"type\":\"about:blank\",\"title\":\"Unprocessable Entity\",\"status\":422," +
+				"\"detail\":\"the request params failed to be validated\",\"instance\":\"/problem\"," +
+				"\"errors\":[{\"field\":\"id\",\"message\":\"param 'id' with value '0' failed on the validation tag 'required'\"}," +
+				"{\"field\":\"name\",\"message\":\"name is required\"}]}",
+		},
+		map[string]interface{}{
+			"url":  "/problem?id=1&name=Lin",
+			"code": http.StatusInternalServerError,
+			"expected": "{\"type\":\"about:blank\",\"title\":\"Internal Server Error\",\"status\":500," +
+				"\"detail\":\"score service is down\",\"instance\":\"/problem\",\"code\":1001}",
+		},
+	}
+	for _, ele := range eles {
+		w := httptest.NewRecorder()
+		engin.ServeHTTP(w, httptest.NewRequest("GET", ele["url"].(string), nil))
+		assert.Equal(t, ele["code"], w.Code)
+		assert.Equal(t, MIMEProblemJSON, w.Header().Get("Content-Type"))
+		assert.Equal(t, ele["expected"], w.Body.String())
+	}
+}
+
+func TestWrappedErrorStatus(t *testing.T) {
+	sleep := func(ctx context.Context, c *Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}
+	fail := func(err error) StepFunc {
+		return func(ctx context.Context, c *Context) error {
+			return err
+		}
+	}
+	newEngine := func(options ...Option) *gin.Engine {
+		engin := gin.New()
+		router := NewRouter(append([]Option{WithRouter(engin.Group("/")), WithStepFailure(StepFailureCollect)}, options...)...)
+		hd := func(path string, steps ...Step) {
+			router.Add(NewInterface(Interface{Path: path, Method: "GET", Steps: steps}, func(c *Context) {}))
+		}
+		hd("/timeout", Step{Name: "s", Timeout: 10 * time.Millisecond, Handle: sleep})
+		hd("/mixed-timeout",
+			Step{Name: "s", Timeout: 10 * time.Millisecond, Handle: sleep},
+			Step{Name: "f", Handle: fail(&SaturatedError{Step: "f", Limit: 1})})
+		hd("/mixed", Step{Name: "a", Handle: fail(&SaturatedError{Step: "a", Limit: 1})},
+			Step{Name: "b", Handle: fail(errors.New("b err"))},
+			Step{Name: "c", Handle: fail(&PanicError{Step: "c", Value: "boom"})})
+		hd("/wrapped", Step{Name: "w", Handle: fail(fmt.Errorf("load: %w", BindErrors{{Field: "id", Source: "json", Expected: "int", Value: "x"}}))})
+		return engin
+	}
+	eles := []struct {
+		url    string
+		code   int
+		status int
+		plain  string
+	}{
+		{"/timeout", 504, 504, "{\"code\":504,\"msg\":\"step s: the step s times out after 10ms\",\"state\":0}"},
+		{"/mixed-timeout", 504, 504, ""},
+		{"/mixed", 502, 502, ""},
+		{"/wrapped", 400, 400, "{\"code\":400,\"msg\":[{\"field\":\"id\",\"source\":\"json\",\"expected\":\"int\",\"value\":\"x\"," +
+			"\"msg\":\"param 'id' with value 'x' is not a valid int\"}],\"state\":0}"},
+	}
+	for _, ele := range eles {
+		w := httptest.NewRecorder()
+		newEngine().ServeHTTP(w, httptest.NewRequest("GET", ele.url, nil))
+		if ele.plain != "" {
+			assert.Equal(t, ele.plain, w.Body.String(), ele.url)
+		}
+		assert.Contains(t, w.Body.String(), fmt.Sprintf("\"code\":%d", ele.code), ele.url)
+		w = httptest.NewRecorder()
+		newEngine(WithProblemDetails()).ServeHTTP(w, httptest.NewRequest("GET", ele.url, nil))
+		assert.Equal(t, ele.status, w.Code, ele.url)
+	}
+	w := httptest.NewRecorder()
+	newEngine(WithProblemDetails()).ServeHTTP(w, httptest.NewRequest("GET", "/wrapped", nil))
+	assert.Contains(t, w.Body.String(), "\"detail\":\"the request params failed to be decoded\"")
+}
+
+func TestGrouteError(t *testing.T) {
+	cause := errors.New("sql: no rows in result set")
+	newEngine := func(options ...Option) *gin.Engine {
+		engin := gin.New()
+		router := NewRouter(append([]Option{WithRouter(engin.Group("/"))}, options...)...)
+		router.Add(NewResultInterface(
+			Interface{Path: "/not-found", Method: "GET"},
+			func(c *Context) (interface{}, error) {
+				return nil, NotFound("student not found").WithCode(2001).WithCause(cause)
+			},
+		))
+		router.Add(NewInterface(
+			Interface{Path: "/conflict", Method: "GET", Steps: []Step{
+				{Name: "check", Handle: func(ctx context.Context, c *Context) error {
+					return fmt.Errorf("check: %w", Conflict("name is taken").
+						WithFields(map[string]string{"name": "name is taken"}).
+						WithDetails(gin.H{"suggest": "Lin2"}))
+				}},
+			}},
+			func(c *Context) {},
+		))
+		router.Add(NewResultInterface(
+			Interface{Path: "/internal", Method: "GET"},
+			func(c *Context) (interface{}, error) {
+				c.ErrCode = 3001
+				return nil, &Error{Cause: cause}
+			},
+		))
+		return engin
+	}
+
+	err := NotFound("student not found").WithCause(cause)
+	assert.True(t, errors.Is(err, cause))
+	assert.Equal(t, "student not found: sql: no rows in result set", err.Error())
+
+	eles := []struct {
+		engin    *gin.Engine
+		url      string
+		code     int
+		expected string
+	}{
+		{newEngine(), "/not-found", 200, "{\"code\":2001,\"msg\":\"student not found\",\"state\":0}"},
+		{newEngine(), "/conflict", 200, "{\"code\":409,\"details\":{\"suggest\":\"Lin2\"},\"msg\":{\"name\":\"name is taken\"},\"state\":0}"},
+		{newEngine(), "/internal", 200, "{\"code\":3001,\"msg\":\"Internal Server Error\",\"state\":0}"},
+		{newEngine(WithProblemDetails()), "/not-found", 404,
+			"{\"type\":\"about:blank\",\"title\":\"Not Found\",\"status\":404,\"detail\":\"student not found\",\"instance\":\"/not-found\",\"code\":2001}"},
+		{newEngine(WithProblemDetails()), "/conflict", 409,
+			"{\"type\":\"about:blank\",\"title\":\"Conflict\",\"status\":409,\"detail\":\"name is taken\",\"instance\":\"/conflict\"," +
+				"\"errors\":[{\"field\":\"name\",\"message\":\"name is taken\"}],\"details\":{\"suggest\":\"Lin2\"}}"},
+	}
+	for _, ele := range eles {
+		w := httptest.NewRecorder()
+		ele.engin.ServeHTTP(w, httptest.NewRequest("GET", ele.url, nil))
+		assert.Equal(t, ele.code, w.Code, ele.url)
+		assert.Equal(t, ele.expected, w.Body.String(), ele.url)
+	}
+}
+
+func TestErrorCodes(t *testing.T) {
+	_, err := NewErrorCodes(
+		ErrorCode{Code: 2001, Status: 404, Message: "student not found"},
+		ErrorCode{Code: 2001, Status: 409, Message: "name is taken"},
+		ErrorCode{Code: 2002, Status: 1000, Message: "bad status"},
+		ErrorCode{Code: 2003},
+		ErrorCode{Code: 2004, Message: "unknown locale", Translations: map[string]string{"xx": "?"}},
+	)
+	assert.Equal(t, "invalid error codes: the code 2001 is duplicated; the status 1000 of the code 2002 is invalid; "+
+		"the code 2003 has no message; the locale xx of the code 2004 is not supported", err.Error())
+
+	codes := MustErrorCodes(
+		ErrorCode{Code: 2002, Status: 409, Message: "name is taken"},
+		ErrorCode{Code: 2001, Status: 404, Message: "student not found", Translations: map[string]string{"zh": "学生不存在"}},
+	)
+	assert.Equal(t, "学生不存在", codes.Message(2001, "zh"))
+	assert.Equal(t, "student not found", codes.Message(2001, "fr"))
+	assert.Equal(t, "", codes.Message(3001, "en"))
+	assert.Equal(t, "| Code | Status | Message | zh |\n| --- | --- | --- | --- |\n"+
+		"| 402 | 422 | the request params failed to be validated | 请求参数校验失败 |\n"+
+		"| 2001 | 404 | student not found | 学生不存在 |\n| 2002 | 409 | name is taken |  |\n", codes.Markdown())
+	// the builtin codes can be redeclared.
+	redeclared := MustErrorCodes(ErrorCode{Code: CodeValidation, Status: 400, Message: "invalid params"})
+	assert.Equal(t, []ErrorCode{{Code: CodeValidation, Status: 400, Message: "invalid params"}}, redeclared.Codes())
+	catalogue, err := codes.JSON()
+	assert.Nil(t, err)
+	assert.Contains(t, string(catalogue), "\"translations\": {\n      \"zh\": \"学生不存在\"\n    }")
+
+	newEngine := func(options ...Option) *gin.Engine {
+		engin := gin.New()
+		router := NewRouter(append([]Option{WithRouter(engin.Group("/")), WithErrorCodes(codes)}, options...)...)
+		router.Add(NewResultInterface(
+			Interface{Path: "/not-found", Method: "GET"},
+			func(c *Context) (interface{}, error) {
+				return nil, codes.Error(2001)
+			},
+		))
+		router.Add(NewResultInterface(
+			Interface{Path: "/conflict", Method: "GET"},
+			func(c *Context) (interface{}, error) {
+				return nil, &Error{Code: 2002, Message: "Lin is taken"}
+			},
+		))
+		router.Add(NewResultInterface(
+			Interface{Path: "/plain", Method: "GET"},
+			func(c *Context) (interface{}, error) {
+				c.ErrCode = 2001
+				return nil, errors.New("sql: no rows in result set")
+			},
+		))
+		router.Add(NewInterface(
+			Interface{Path: "/validate", Method: "GET", Param: struct {
+				ID int `form:"id" binding:"required"`
+			}{}},
+			func(c *Context) {},
+		))
+		return engin
+	}
+	eles := []struct {
+		engin    *gin.Engine
+		url      string
+		lang     string
+		code     int
+		expected string
+	}{
+		{newEngine(), "/not-found", "en", 200, "{\"code\":2001,\"msg\":\"student not found\",\"state\":0}"},
+		{newEngine(), "/not-found", "zh", 200, "{\"code\":2001,\"msg\":\"学生不存在\",\"state\":0}"},
+		{newEngine(), "/conflict", "zh", 200, "{\"code\":2002,\"msg\":\"Lin is taken\",\"state\":0}"},
+		{newEngine(WithProblemDetails()), "/not-found", "zh", 404,
+			"{\"type\":\"about:blank\",\"title\":\"Not Found\",\"status\":404,\"detail\":\"学生不存在\",\"instance\":\"/not-found\",\"code\":2001}"},
+		{newEngine(WithProblemDetails()), "/conflict", "en", 409,
+			"{\"type\":\"about:blank\",\"title\":\"Conflict\",\"status\":409,\"detail\":\"Lin is taken\",\"instance\":\"/conflict\",\"code\":2002}"},
+		{newEngine(), "/plain", "zh", 200, "{\"code\":2001,\"msg\":\"学生不存在\",\"state\":0}"},
+		{newEngine(WithProblemDetails()), "/plain", "en", 404,
+			"{\"type\":\"about:blank\",\"title\":\"Not Found\",\"status\":404,\"detail\":\"student not found\",\"instance\":\"/plain\",\"code\":2001}"},
+		{newEngine(WithProblemDetails()), "/validate", "zh", 422,
+			"{\"type\":\"about:blank\",\"title\":\"Unprocessable Entity\",\"status\":422,\"detail\":\"请求参数校验失败\",\"instance\":\"/validate\"," +
+				"\"errors\":[{\"field\":\"id\",\"message\":\"param 'id' with value '0' failed on the validation tag 'required'\"}]}"},
+	}
+	for _, ele := range eles {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest("GET", ele.url, nil)
+		req.Header.Set("Accept-Language", ele.lang)
+		ele.engin.ServeHTTP(w, req)
+		assert.Equal(t, ele.code, w.Code, ele.url)
+		assert.Equal(t, ele.expected, w.Body.String(), ele.url)
+	}
+}
+
+func TestRouterMethods(t *testing.T) {
+	engin := gin.New()
+	router := NewRouter(WithRouter(engin.Group("/")))
+	handle := func(c *Context) {
+		c.GinContext.String(http.StatusOK, c.GinContext.Request.Method)
+	}
+	router.Add(NewInterface(Interface{Path: "/options", Method: "options"}, handle))
+	router.Add(NewInterface(Interface{Path: "/any", Method: "Any"}, handle))
+	router.Add(NewInterface(Interface{Path: "/multi", Method: "GET", Methods: []string{"put", "get", "DELETE"}}, handle))
+	router.Add(NewInterface(Interface{Path: "/default"}, handle))
+
+	eles := []struct {
+		method string
+		url    string
+		code   int
+	}{
+		{"OPTIONS", "/options", 200},
+		{"GET", "/options", 404},
+		{"GET", "/any", 200},
+		{"TRACE", "/any", 200},
+		{"OPTIONS", "/any", 200},
+		{"GET", "/multi", 200},
+		{"PUT", "/multi", 200},
+		{"DELETE", "/multi", 200},
+		{"POST", "/multi", 404},
+		{"POST", "/default", 200},
+	}
+	for _, ele := range eles {
+		w := httptest.NewRecorder()
+		engin.ServeHTTP(w, httptest.NewRequest(ele.method, ele.url, nil))
+		assert.Equal(t, ele.code, w.Code, ele.method+" "+ele.url)
+		if ele.code == 200 {
+			assert.Equal(t, ele.method, w.Body.String(), ele.method+" "+ele.url)
+		}
+	}
+	assert.Equal(t, "the interface [/typo]: unknown method \"GTE\"", panicMessage(func() {
+		router.Add(NewInterface(Interface{Path: "/typo", Method: "GTE"}, handle))
+	}))
+	assert.Equal(t, "the interface [/typo]: unknown method \"\"", panicMessage(func() {
+		router.Add(NewInterface(Interface{Path: "/typo", Methods: []string{""}}, handle))
+	}))
+}
+
+type validateItem struct {
+	Qty int `json:"qty" binding:"min=1" err-max:"qty is too large"`
+}
+
+type validateParams struct {
+	Name  string         `json:"name" binding:"required,max=10|eq=0" err-required:"name is required" err-eq:"name is empty"`
+	Age   int            `json:"age" binding:"unknownrule"`
+	Items []validateItem `json:"items" binding:"dive"`
+}
+
+type validateStruct struct{}
+
+func (s *validateStruct) Info() Interface {
+	return Interface{Path: "/info", Method: "GET", Handle: func(c *Context) {}}
+}
+
+func (s *validateStruct) Typo() Interface {
+	return Interface{Path: "/typo", Method: "GTE", Handle: func(c *Context) {}}
+}
+
+func TestRouterValidate(t *testing.T) {
+	_, err := NewRouterE()
+	assert.Equal(t, "gin router must be set and not be nil", err.Error())
+	engin := gin.New()
+	engin.GET("/docs", func(c *gin.Context) {})
+	_, err = NewRouterE(WithRouter(engin.Group("/")), WithOpenAPIPath("/docs"))
+	assert.Contains(t, err.Error(), "the OpenAPI document [GET /docs]: ")
+
+	versions := map[string]Option{
+		"v8":  func(opts *Options) {},
+		"v9":  WithVaidatorV9("en"),
+		"v10": WithValidatorV10("en"),
+	}
+	for version, option := range versions {
+		engin := gin.New()
+		router, err := NewRouterE(WithRouter(engin.Group("/")), option)
+		assert.Nil(t, err)
+		handle := func(c *Context) {}
+		assert.Nil(t, router.AddE(NewInterface(Interface{Path: "/student", Method: "POST", Methods: []string{"PUT"}, Param: validateParams{}}, handle)))
+		assert.Nil(t, router.AddE(NewInterface(Interface{Path: "", Method: "GET", Param: "name"}, handle)))
+		assert.Equal(t, "the interface [PUT /student]: duplicated with the interface added before",
+			router.AddE(NewInterface(Interface{Path: "/student", Method: "PUT"}, handle)).Error())
+		assert.Equal(t, "the given interface [realType:groute_test.validateStruct,baseType:struct] is not a pointer of struct",
+			router.AddE(validateStruct{}).Error())
+		err = router.AddE(&validateStruct{})
+		assert.Equal(t, "the interface [/typo]: unknown method \"GTE\"", err.Error())
+		assert.Nil(t, router.AddE(NewInterface(Interface{Path: "/detail/:id", Method: "GET"}, handle)))
+		assert.Contains(t, router.AddE(NewInterface(Interface{Path: "/detail/:name", Method: "GET"}, handle)).Error(),
+			"the interface [GET /detail/:name]: ")
+		assert.Equal(t, "the interface [GET /no-handle]: no Handle", router.AddE(Interface{Path: "/no-handle", Method: "GET"}).Error())
+
+		err = router.Validate()
+		errs, ok := err.(RouteErrors)
+		assert.True(t, ok, version)
+		msgs := make([]string, 0, len(errs))
+		for _, e := range errs {
+			msgs = append(msgs, e.Error())
+		}
+		assert.Equal(t, 9, len(msgs), strings.Join(msgs, "\n"))
+		assert.Equal(t, "the interface [PUT /student]: duplicated with the interface added before", msgs[0], version)
+		assert.Contains(t, msgs[3], "the interface [GET /detail/:name]: ", version)
+		assert.Equal(t, "the interface [GET /no-handle]: no Handle", msgs[4], version)
+		assert.Contains(t, msgs[5], "the interface [POST,PUT /student]: the validator tags of groute_test.validateParams are malformed: ", version)
+		assert.Contains(t, msgs[5], "Age", version)
+		assert.Equal(t, "the interface [POST,PUT /student]: the tag err-max of the field validateItem.Qty refers to no rule of the tag binding", msgs[6], version)
+		assert.Equal(t, "the interface [GET ]: empty path", msgs[7], version)
+		assert.Equal(t, "the interface [GET ]: the Param string is not a struct", msgs[8], version)
+	}
+}
+
+func TestRouterRegisterMethods(t *testing.T) {
+	engin := gin.New()
+	router, err := NewRouterE(WithRouter(engin.Group("/")), WithRoutesPath("/routes"))
+	assert.Nil(t, err)
+	handle := func(c *Context) {}
+	assert.Nil(t, router.AddE(NewInterface(Interface{Path: "/detail/:id", Method: "GET"}, handle)))
+	err = router.AddE(NewInterface(Interface{Path: "/detail/:name", Method: "POST", Methods: []string{"GET"}}, handle))
+	assert.Contains(t, err.Error(), "the interface [GET /detail/:name]: ")
+	// the former method of the rejected interface is not registered.
+	assert.Equal(t, 1, len(router.Routes()))
+	w := httptest.NewRecorder()
+	engin.ServeHTTP(w, httptest.NewRequest("POST", "/detail/x", nil))
+	assert.Equal(t, 404, w.Code)
+	// the routes served by the router are checked too.
+	err = router.AddE(NewInterface(Interface{Path: "/routes", Method: "PUT", Methods: []string{"GET"}}, handle))
+	assert.Contains(t, err.Error(), "the interface [GET /routes]: ")
+	assert.Nil(t, router.AddE(NewInterface(Interface{Path: "/detail/:id", Method: "PUT", Methods: []string{"DELETE"}}, handle)))
+	assert.Equal(t, 3, len(router.Routes()))
+
+	// the interfaces without Handle are rejected instead of panicking on requests.
+	typed := NewTypedInterface(TypedInterface[typedParams, typedResponse]{Path: "/typed", Method: "GET"}, nil)
+	assert.Equal(t, "the interface [GET /typed]: no Handle", router.AddE(typed).Error())
+	assert.Equal(t, "the interface [GET /plain]: no Handle", router.AddE(NewInterface(Interface{Path: "/plain", Method: "GET"}, nil)).Error())
+	assert.Equal(t, 3, len(router.Routes()))
+}
+
+type routesStruct struct{}
+
+func (s *routesStruct) Info() Interface {
+	return NewInterface(Interface{Path: "/info", Method: "GET", Methods: []string{"HEAD"}, Param: validateItem{}}, func(c *Context) {})
+}
+
+func TestRouterRoutes(t *testing.T) {
+	engin := gin.New()
+	router := NewRouter(WithRouter(engin.Group("/api")), WithRoutesPath("/routes"))
+	router.Add(&routesStruct{})
+	router.Add(NewInterface(Interface{
+		Path:            "/student",
+		AsyncHandleFunc: ErrHandleFuncChain{func(c *Context) error { return nil }},
+		Steps: []Step{
+			{Name: "load", Handle: func(ctx context.Context, c *Context) error { return nil }},
+		},
+	}, func(c *Context) {}))
+
+	routes := router.Routes()
+	assert.Equal(t, 3, len(routes))
+	assert.Equal(t, RouteInfo{
+		Method:    "GET",
+		Path:      "/api/info",
+		Param:     "groute_test.validateItem",
+		ParamType: reflect.TypeOf(validateItem{}),
+		Source:    "(*groute_test.routesStruct).Info",
+	}, routes[0])
+	assert.Equal(t, "HEAD", routes[1].Method)
+	assert.Equal(t, RouteInfo{Method: "POST", Path: "/api/student", Steps: []string{"async-0", "load"}}, routes[2])
+
+	w := httptest.NewRecorder()
+	engin.ServeHTTP(w, httptest.NewRequest("GET", "/api/routes", nil))
+	assert.Equal(t, 200, w.Code)
+	assert.Equal(t, "[{\"method\":\"GET\",\"path\":\"/api/info\",\"param\":\"groute_test.validateItem\",\"source\":\"(*groute_test.routesStruct).Info\"},"+
+		"{\"method\":\"HEAD\",\"path\":\"/api/info\",\"param\":\"groute_test.validateItem\",\"source\":\"(*groute_test.routesStruct).Info\"},"+
+		"{\"method\":\"POST\",\"path\":\"/api/student\",\"steps\":[\"async-0\",\"load\"]}]", w.Body.String())
+}
+
+type StudentRecord struct{}
+
+func (s *StudentRecord) GetInfo() Interface {
+	return NewInterface(Interface{}, func(c *Context) {})
+}
+
+func (s *StudentRecord) PostScore() Interface {
+	return NewInterface(Interface{}, func(c *Context) {})
+}
+
+func (s *StudentRecord) DeleteUserIDList() Interface {
+	return NewInterface(Interface{}, func(c *Context) {})
+}
+
+func (s *StudentRecord) Get() Interface {
+	return NewInterface(Interface{}, func(c *Context) {})
+}
+
+func (s *StudentRecord) Getaway() Interface {
+	return NewInterface(Interface{Method: "PUT"}, func(c *Context) {})
+}
+
+func (s *StudentRecord) PutName() Interface {
+	return NewInterface(Interface{Path: "/rename", Method: "PATCH"}, func(c *Context) {})
+}
+
+func TestRouterConvention(t *testing.T) {
+	eles := []struct {
+		convention Convention
+		expected   []string
+	}{
+		{Convention{}, []string{
+			"DELETE /user-id-list", "GET /", "GET /info", "PUT /getaway", "POST /score", "PATCH /rename",
+		}},
+		{Convention{Casing: CasingSnake, StructPrefix: true}, []string{
+			"DELETE /student_record/user_id_list", "GET /student_record", "GET /student_record/info",
+			"PUT /student_record/getaway", "POST /student_record/score", "PATCH /rename",
+		}},
+	}
+	for _, ele := range eles {
+		engin := gin.New()
+		router := NewRouter(WithRouter(engin.Group("/")), WithConvention(ele.convention))
+		router.Add(&StudentRecord{})
+		var routes []string
+		for _, rt := range router.Routes() {
+			routes = append(routes, rt.Method+" "+rt.Path)
+		}
+		assert.Equal(t, ele.expected, routes)
+	}
+}
+
 func TestRouterMiddleware(t *testing.T) {
 	hd := NewInterface(
 		Interface{
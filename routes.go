@@ -0,0 +1,85 @@
+// MIT License
+
+// Copyright (c) 2019 tanzy2018
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package groute
+
+import (
+	"fmt"
+	"net/http"
+	"reflect"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RouteInfo - the interface registered by the router.
+type RouteInfo struct {
+	Method string `json:"method"`
+	// Path - full path including the prefix of the router group.
+	Path string `json:"path"`
+	// Param - name of the Param type,empty without Param.
+	Param string `json:"param,omitempty"`
+	// ParamType - type of the Param,nil without Param.
+	ParamType reflect.Type `json:"-"`
+	// Steps - names of the steps,the AsyncHandleFunc and SyncHandleFunc included.
+	Steps []string `json:"steps,omitempty"`
+	// Source - the struct method returning the interface,e.g. "(*main.Student).Info",
+	// empty if the interface is added directly.
+	Source string `json:"source,omitempty"`
+}
+
+// WithRoutesPath - serve the routes of the router in json on the path for debugging,e.g. "/debug/routes".
+func WithRoutesPath(path string) Option {
+	return func(opts *Options) {
+		opts.routesPath = path
+	}
+}
+
+// Routes - all the interfaces added to the router in order,one for each method.
+func (r *Router) Routes() []RouteInfo {
+	routes := make([]RouteInfo, 0, len(r.routes))
+	for _, rt := range r.routes {
+		info := RouteInfo{
+			Method: rt.method,
+			Path:   rt.path,
+			Steps:  append([]string(nil), rt.steps...),
+			Source: rt.source,
+		}
+		if rt.inter.Param != nil {
+			info.ParamType = reflect.TypeOf(rt.inter.Param)
+			info.Param = info.ParamType.String()
+		}
+		routes = append(routes, info)
+	}
+	return routes
+}
+
+// serve the routes,which are listed on every request
+// so that interfaces added later are included.
+func (r *Router) serveRoutes(path string) error {
+	err := r.handle(http.MethodGet, path, func(c *gin.Context) {
+		c.JSON(http.StatusOK, r.Routes())
+	})
+	if err != nil {
+		return fmt.Errorf("the routes [GET %s]: %w", path, err)
+	}
+	return nil
+}
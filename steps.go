@@ -0,0 +1,561 @@
+// MIT License
+
+// Copyright (c) 2019 tanzy2018
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package groute
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime/debug"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// StepFunc - handle function of the step,ctx is canceled once any step fails.
+type StepFunc func(ctx context.Context, c *Context) error
+
+// Step - the named middleware which runs as soon as all the steps it's after finish.
+type Step struct {
+	// Name - unique in the Interface.
+	Name string
+	// After - names of the steps this step depends on.
+	After []string
+	// Handle
+	Handle StepFunc
+	// Timeout - deadline of the step,default the StepTimeout of the Interface.
+	Timeout time.Duration
+	// Optional - the error of the step is recorded in Context.FailedSteps instead of failing the request,
+	// the steps depending on it still run.
+	Optional bool
+	// Fallback - called with the error of the step,e.g. to set the fallback value,
+	// the step is optional if set.
+	Fallback func(c *Context, err error)
+	// Retries - times to retry the failed step,except for the panics.
+	Retries int
+	// Backoff - delay of the first retry,doubled for each next one.
+	Backoff time.Duration
+	// ignoresCtx - the step converted from AsyncHandleFunc or SyncHandleFunc,
+	// which only sees Context.ClientContext.
+	ignoresCtx bool
+}
+
+// FallbackValue - the Fallback setting the value of the key by Set.
+func FallbackValue[T any](key string, v T) func(c *Context, err error) {
+	return func(c *Context, err error) {
+		Set(c, key, v)
+	}
+}
+
+// TimeoutError - the step runs out of its timeout or the budget of all the steps.
+type TimeoutError struct {
+	// Step - name of the step timing out.
+	Step string
+	// Timeout - the exceeded duration.
+	Timeout time.Duration
+	// Budget - the budget of all the steps is exceeded rather than the timeout of the step.
+	Budget bool
+}
+
+func (e *TimeoutError) Error() string {
+	if e.Budget {
+		return fmt.Sprintf("the steps exceed the budget %s at the step %s", e.Timeout, e.Step)
+	}
+	return fmt.Sprintf("the step %s times out after %s", e.Step, e.Timeout)
+}
+
+func (e *TimeoutError) Unwrap() error {
+	return context.DeadlineExceeded
+}
+
+// StepFailure - how the steps end once any of them fails.
+type StepFailure int
+
+const (
+	// StepFailureWait - cancel the other steps and wait for the running ones to return,
+	// so that no step runs after the response,the default one.
+	StepFailureWait StepFailure = iota + 1
+	// StepFailureDetach - cancel the other steps and leave the running ones behind,
+	// so that the steps ignoring the context don't hold the response.
+	// The steps read the copy of the gin context,which writes the response and sets the keys
+	// until they're left behind,then the writes and the changes of the Context are dropped.
+	StepFailureDetach
+	// StepFailureCollect - keep running the steps not depending on the failed ones,
+	// then pass all the errors in StepErrors.
+	StepFailureCollect
+)
+
+// StepError - error of the step.
+type StepError struct {
+	Step string
+	Err  error
+}
+
+func (e *StepError) Error() string {
+	return fmt.Sprintf("step %s: %v", e.Step, e.Err)
+}
+
+func (e *StepError) Unwrap() error {
+	return e.Err
+}
+
+// StepErrors - errors of all the failed steps in the order they fail.
+type StepErrors []*StepError
+
+func (e StepErrors) Error() string {
+	msgs := make([]string, 0, len(e))
+	for _, err := range e {
+		msgs = append(msgs, err.Error())
+	}
+	return strings.Join(msgs, "; ")
+}
+
+func (e StepErrors) Unwrap() []error {
+	errs := make([]error, 0, len(e))
+	for _, err := range e {
+		errs = append(errs, err)
+	}
+	return errs
+}
+
+// PanicError - the step panics.
+type PanicError struct {
+	Step string
+	// Value - the value passed to panic.
+	Value interface{}
+	// Stack - stack trace of the goroutine when panicking.
+	Stack []byte
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("the step %s panics: %v", e.Step, e.Value)
+}
+
+// Unwrap - the value passed to panic if it's an error.
+func (e *PanicError) Unwrap() error {
+	err, _ := e.Value.(error)
+	return err
+}
+
+// WithPanicReporter - set the hook reporting the panics of the steps,
+// e.g. to log the stack or send to the error tracker,
+// the panic is recovered and passed to the ErrHandle as *PanicError anyway.
+func WithPanicReporter(report func(c *Context, err *PanicError)) Option {
+	return func(opts *Options) {
+		opts.panicReporter = report
+	}
+}
+
+// WithStepFailure - set how the steps of all the interfaces end once any of them fails,
+// overridden by Interface.StepFailure; default StepFailureWait.
+func WithStepFailure(failure StepFailure) Option {
+	return func(opts *Options) {
+		opts.stepFailure = failure
+	}
+}
+
+// WithStepTimeout - set the default timeout of each step of all the interfaces,
+// overridden by Interface.StepTimeout and Step.Timeout.
+func WithStepTimeout(timeout time.Duration) Option {
+	return func(opts *Options) {
+		opts.stepTimeout = timeout
+	}
+}
+
+// WithStepBudget - set the default budget of all the steps of a request,
+// overridden by Interface.StepBudget.
+func WithStepBudget(budget time.Duration) Option {
+	return func(opts *Options) {
+		opts.stepBudget = budget
+	}
+}
+
+// steps - all the steps of the Interface,
+// AsyncHandleFunc are converted into the steps named async-<index> without dependencies,
+// SyncHandleFunc into the steps named sync-<index> running in order after all the async ones.
+func (inter Interface) steps() []Step {
+	steps := make([]Step, 0, len(inter.AsyncHandleFunc)+len(inter.SyncHandleFunc)+len(inter.Steps))
+	var asyncNames []string
+	for i, fn := range inter.AsyncHandleFunc {
+		name := fmt.Sprintf("async-%d", i)
+		steps = append(steps, Step{Name: name, Handle: errHandleStep(fn), ignoresCtx: true})
+		asyncNames = append(asyncNames, name)
+	}
+	after := asyncNames
+	for i, fn := range inter.SyncHandleFunc {
+		name := fmt.Sprintf("sync-%d", i)
+		steps = append(steps, Step{Name: name, After: after, Handle: errHandleStep(fn), ignoresCtx: true})
+		after = []string{name}
+	}
+	return append(steps, inter.Steps...)
+}
+
+func errHandleStep(fn ErrHandleFunc) StepFunc {
+	return func(_ context.Context, c *Context) error {
+		return fn(c)
+	}
+}
+
+// stepOptions - options of all the steps of the Interface.
+type stepOptions struct {
+	// timeout - timeout of the steps without their own one.
+	timeout time.Duration
+	budget  time.Duration
+	failure StepFailure
+	// panicReporter - the hook reporting the panics.
+	panicReporter func(*Context, *PanicError)
+	// limiter - limit the concurrent steps.
+	limiter *limiter
+}
+
+// stepGraph - the steps of the Interface checked at registration.
+type stepGraph struct {
+	steps []Step
+	// timeouts - timeout of each step.
+	timeouts []time.Duration
+	// budget - budget of all the steps.
+	budget        time.Duration
+	failure       StepFailure
+	panicReporter func(*Context, *PanicError)
+	limiter       *limiter
+	// deps - count of the dependencies of each step.
+	deps []int
+	// next - indexes of the steps depending on each step.
+	next [][]int
+}
+
+// newStepGraph - check the names,the dependencies and the cycles of the steps.
+func newStepGraph(steps []Step, opts stepOptions) (*stepGraph, error) {
+	g := &stepGraph{
+		steps:         steps,
+		timeouts:      make([]time.Duration, len(steps)),
+		budget:        opts.budget,
+		failure:       opts.failure,
+		panicReporter: opts.panicReporter,
+		limiter:       opts.limiter,
+		deps:          make([]int, len(steps)),
+		next:          make([][]int, len(steps)),
+	}
+	index := make(map[string]int, len(steps))
+	for i, s := range steps {
+		g.timeouts[i] = s.Timeout
+		if g.timeouts[i] <= 0 {
+			g.timeouts[i] = opts.timeout
+		}
+		if s.Name == "" {
+			return nil, fmt.Errorf("the step [index:%d] has no name", i)
+		}
+		if s.Handle == nil {
+			return nil, fmt.Errorf("the step %s has no Handle", s.Name)
+		}
+		if _, ok := index[s.Name]; ok {
+			return nil, fmt.Errorf("the step %s is duplicated", s.Name)
+		}
+		index[s.Name] = i
+	}
+	for i, s := range steps {
+		for _, name := range s.After {
+			j, ok := index[name]
+			if !ok {
+				return nil, fmt.Errorf("the step %s depends on the unknown step %s", s.Name, name)
+			}
+			g.deps[i]++
+			g.next[j] = append(g.next[j], i)
+		}
+	}
+	// the steps left after sorting topologically are in or after a cycle.
+	deps := append([]int(nil), g.deps...)
+	var ready []int
+	for i, n := range deps {
+		if n == 0 {
+			ready = append(ready, i)
+		}
+	}
+	sorted := 0
+	for len(ready) > 0 {
+		i := ready[0]
+		ready = ready[1:]
+		sorted++
+		for _, j := range g.next[i] {
+			if deps[j]--; deps[j] == 0 {
+				ready = append(ready, j)
+			}
+		}
+	}
+	if sorted < len(steps) {
+		var names []string
+		for i, n := range deps {
+			if n > 0 {
+				names = append(names, steps[i].Name)
+			}
+		}
+		return nil, fmt.Errorf("the steps %s form a cycle", strings.Join(names, ","))
+	}
+	return g, nil
+}
+
+// mayLeave - the step may be left behind running after the request,
+// then its attempts run on the views of the Context.
+func (g *stepGraph) mayLeave(i int) bool {
+	return g.failure == StepFailureDetach || g.steps[i].ignoresCtx && (g.timeouts[i] > 0 || g.budget > 0)
+}
+
+type stepResult struct {
+	index int
+	err   error
+}
+
+// run - run each step as soon as its dependencies finish and return the error by the StepFailure,
+// ctx of the steps is always canceled before returning.
+// The only ready step runs in the calling goroutine,the others concurrently.
+func (g *stepGraph) run(ctx context.Context, c *Context) error {
+	if len(g.steps) == 0 {
+		return nil
+	}
+	budget := ctx
+	if g.budget > 0 {
+		var cancel context.CancelFunc
+		budget, cancel = context.WithTimeout(ctx, g.budget)
+		defer cancel()
+	}
+	ctx, cancel := context.WithCancel(budget)
+	defer cancel()
+	// the steps converted from the handle funcs call the backend services with the ClientContext.
+	clientContext := c.ClientContext
+	c.ClientContext = ctx
+	run := &stepRun{c: c}
+	defer func() {
+		// the steps left behind run on their views,which no longer touch c.
+		run.detach()
+		c.ClientContext = clientContext
+	}()
+	deps := append([]int(nil), g.deps...)
+	// buffered so that the detached steps never block.
+	results := make(chan stepResult, len(g.steps))
+	running := 0
+	var ready []int
+	for i, n := range deps {
+		if n == 0 {
+			ready = append(ready, i)
+		}
+	}
+	var (
+		first error
+		errs  StepErrors
+	)
+	// held - count of the steps holding the slots of the limiter.
+	var held int32
+	for {
+		lone := len(ready) == 1 && running == 0
+		for _, i := range ready {
+			i := i
+			running++
+			release, concurrent, err := g.limiter.acquire(ctx, g.steps[i].Name, atomic.LoadInt32(&held) > 0)
+			switch {
+			case err != nil:
+				results <- stepResult{index: i, err: err}
+			case !concurrent:
+				results <- stepResult{index: i, err: g.runStep(ctx, budget, run, c, i)}
+			case lone:
+				// the only ready step runs in the calling goroutine,holding its slots.
+				results <- stepResult{index: i, err: g.runStep(ctx, budget, run, c, i)}
+				release()
+			default:
+				atomic.AddInt32(&held, 1)
+				go func() {
+					defer func() {
+						release()
+						atomic.AddInt32(&held, -1)
+					}()
+					results <- stepResult{index: i, err: g.runStep(ctx, budget, run, c, i)}
+				}()
+			}
+		}
+		ready = ready[:0]
+		if running == 0 {
+			break
+		}
+		res := <-results
+		running--
+		if res.err != nil {
+			switch g.failure {
+			case StepFailureDetach:
+				return res.err
+			case StepFailureCollect:
+				// the steps depending on the failed one never get ready.
+				errs = append(errs, &StepError{Step: g.steps[res.index].Name, Err: res.err})
+			default:
+				if first == nil {
+					first = res.err
+					cancel()
+				}
+			}
+			continue
+		}
+		if first != nil {
+			continue
+		}
+		for _, j := range g.next[res.index] {
+			if deps[j]--; deps[j] == 0 {
+				ready = append(ready, j)
+			}
+		}
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return first
+}
+
+// runStep - run the step by its policy,
+// the error of the optional step is recorded in the Context instead of returned.
+func (g *stepGraph) runStep(ctx context.Context, budget context.Context, run *stepRun, c *Context, i int) error {
+	step := g.steps[i]
+	err := g.attemptStep(ctx, budget, run, c, i)
+	backoff := step.Backoff
+	for n := 0; n < step.Retries && err != nil && ctx.Err() == nil; n++ {
+		if _, ok := err.(*PanicError); ok {
+			break
+		}
+		select {
+		case <-ctx.Done():
+		case <-time.After(backoff):
+			err = g.attemptStep(ctx, budget, run, c, i)
+		}
+		backoff *= 2
+	}
+	if err == nil || !step.Optional && step.Fallback == nil {
+		return err
+	}
+	if step.Fallback != nil {
+		fc := c
+		var view *stepView
+		if g.mayLeave(i) {
+			if view = run.view(); view == nil {
+				// the request no longer waits for the step.
+				return err
+			}
+			fc = view.Context
+		}
+		// the panic of the fallback fails the step.
+		fallbackErr := g.recoverStep(fc, step.Name, func() error {
+			step.Fallback(fc, err)
+			return nil
+		})
+		if view != nil {
+			view.merge()
+		}
+		if fallbackErr != nil {
+			return fallbackErr
+		}
+	}
+	c.addFailedStep(&StepError{Step: step.Name, Err: err})
+	return nil
+}
+
+// attemptStep - run the step once within its timeout and the budget,
+// the step is left behind running once the deadline is exceeded by StepFailureDetach
+// or if it's converted from the handle funcs,which can't see ctx,
+// otherwise it's waited for.
+// The attempt which may be left behind runs on the view of c,merged once it returns in time.
+func (g *stepGraph) attemptStep(ctx context.Context, budget context.Context, run *stepRun, c *Context, i int) error {
+	step, timeout := g.steps[i], g.timeouts[i]
+	var view *stepView
+	if g.mayLeave(i) {
+		if view = run.view(); view == nil {
+			// the request no longer waits for the step.
+			return context.Canceled
+		}
+		c = view.Context
+		defer view.merge()
+	}
+	if timeout <= 0 && g.budget <= 0 {
+		return g.callStep(ctx, c, step)
+	}
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	done := make(chan error, 1)
+	go func() {
+		done <- g.callStep(ctx, c, step)
+	}()
+	var err error
+	select {
+	case err = <-done:
+		if err == nil || !errors.Is(err, context.DeadlineExceeded) {
+			return err
+		}
+	case <-ctx.Done():
+		err = ctx.Err()
+		if view != nil {
+			view.leave()
+		} else {
+			<-done
+		}
+	}
+	switch {
+	case budget.Err() == context.DeadlineExceeded:
+		return &TimeoutError{Step: step.Name, Timeout: g.budget, Budget: true}
+	case ctx.Err() == context.DeadlineExceeded:
+		return &TimeoutError{Step: step.Name, Timeout: timeout}
+	}
+	return err
+}
+
+// callStep - call the step and recover its panic as *PanicError.
+func (g *stepGraph) callStep(ctx context.Context, c *Context, step Step) error {
+	return g.recoverStep(c, step.Name, func() error {
+		return step.Handle(ctx, c)
+	})
+}
+
+// recoverStep - call fn of the step and recover its panic as *PanicError reported by the panicReporter.
+func (g *stepGraph) recoverStep(c *Context, name string, fn func() error) (err error) {
+	defer func() {
+		if v := recover(); v != nil {
+			e := &PanicError{Step: name, Value: v, Stack: debug.Stack()}
+			if g.panicReporter != nil {
+				g.panicReporter(c, e)
+			}
+			err = e
+		}
+	}()
+	return fn()
+}
+
+// mergeContext - ctx also canceled once other is done,e.g. the request.
+func mergeContext(ctx context.Context, other context.Context) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(ctx)
+	if other.Done() != nil {
+		go func() {
+			select {
+			case <-other.Done():
+				cancel()
+			case <-ctx.Done():
+			}
+		}()
+	}
+	return ctx, cancel
+}
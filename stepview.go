@@ -0,0 +1,284 @@
+// MIT License
+
+// Copyright (c) 2019 tanzy2018
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package groute
+
+import (
+	"bufio"
+	"errors"
+	"net"
+	"net/http"
+	"reflect"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+)
+
+// stepRun - the steps of a request,
+// the attempts which may be left behind run on their own views of the Context.
+type stepRun struct {
+	mu sync.Mutex
+	// c - the Context of the request.
+	c *Context
+	// detached - the request no longer waits for the steps.
+	detached bool
+}
+
+// detach - the request goes on without the running steps,
+// their views are no longer merged and write no response.
+func (r *stepRun) detach() {
+	r.mu.Lock()
+	r.detached = true
+	r.mu.Unlock()
+}
+
+// stepView - the Context of the attempt which may be left behind,
+// merged into the Context of the request once the attempt returns while the request waits for it,
+// so that the request never reads what the steps left behind write.
+type stepView struct {
+	*Context
+	run *stepRun
+	// ginContext - the gin context of the request,the view reads its copy.
+	ginContext *gin.Context
+	// the values when the view is created.
+	keys    map[string]interface{}
+	status  int
+	param   interface{}
+	errCode interface{}
+	extra   map[string]interface{}
+	// left - the attempt is left behind.
+	left bool
+}
+
+// view - the view for an attempt of the step,nil once the request no longer waits for the steps.
+func (r *stepRun) view() *stepView {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.detached {
+		return nil
+	}
+	c := r.c
+	gc := c.GinContext.Copy()
+	v := &stepView{
+		run:        r,
+		ginContext: c.GinContext,
+		keys:       make(map[string]interface{}, len(gc.Keys)),
+		status:     gc.Writer.Status(),
+		param:      c.Param,
+		errCode:    c.ErrCode,
+		extra:      c.Extra,
+	}
+	for key, value := range gc.Keys {
+		v.keys[key] = value
+	}
+	gc.Writer = &stepWriter{ResponseWriter: gc.Writer, view: v}
+	v.Context = &Context{
+		shared:        c,
+		GinContext:    gc,
+		ClientContext: c.ClientContext,
+		Param:         c.Param,
+		ErrCode:       c.ErrCode,
+		Extra:         c.Extra,
+		ErrHandle:     c.ErrHandle,
+		Locale:        c.Locale,
+		ErrorCodes:    c.ErrorCodes,
+	}
+	return v
+}
+
+// leave - the attempt is left behind running.
+func (v *stepView) leave() {
+	v.run.mu.Lock()
+	v.left = true
+	v.run.mu.Unlock()
+}
+
+// isLeft - called with the lock of the run held.
+func (v *stepView) isLeft() bool {
+	return v.left || v.run.detached
+}
+
+// merge - set the fields and the gin keys changed by the returned attempt to the request,
+// unless it's left behind.
+func (v *stepView) merge() {
+	v.run.mu.Lock()
+	defer v.run.mu.Unlock()
+	if v.isLeft() {
+		return
+	}
+	c := v.run.c
+	if !sameValue(v.Param, v.param) {
+		c.Param = v.Param
+	}
+	if !sameValue(v.ErrCode, v.errCode) {
+		c.ErrCode = v.ErrCode
+	}
+	if !sameValue(v.Extra, v.extra) {
+		c.Extra = v.Extra
+	}
+	for key, value := range v.GinContext.Keys {
+		if old, ok := v.keys[key]; !ok || !sameValue(old, value) {
+			v.ginContext.Set(key, value)
+		}
+	}
+}
+
+// sameValue - reports whether b is still a,the references are compared by their addresses.
+func sameValue(a, b interface{}) bool {
+	va, vb := reflect.ValueOf(a), reflect.ValueOf(b)
+	if !va.IsValid() || !vb.IsValid() {
+		return va.IsValid() == vb.IsValid()
+	}
+	if va.Type() != vb.Type() {
+		return false
+	}
+	switch va.Kind() {
+	case reflect.Map, reflect.Slice, reflect.Ptr, reflect.Func, reflect.Chan, reflect.UnsafePointer:
+		return va.Pointer() == vb.Pointer()
+	}
+	if va.Type().Comparable() {
+		return a == b
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+// errStepLeft - the attempt left behind can't hijack the connection.
+var errStepLeft = errors.New("the step is left behind")
+
+// stepWriter - the writer of the copied gin context,
+// which writes the response with the status set by the copy until the attempt is left behind.
+type stepWriter struct {
+	// ResponseWriter - the writer of the copy,holding the status set by gin.Context.Status.
+	gin.ResponseWriter
+	view *stepView
+	// header - the header set by the attempt left behind.
+	header http.Header
+}
+
+// response - the writer of the request,nil once the attempt is left behind,
+// called with the lock of the run held.
+func (w *stepWriter) response() gin.ResponseWriter {
+	if w.view.isLeft() {
+		return nil
+	}
+	return w.view.ginContext.Writer
+}
+
+// writeStatus - the writer of the request with the status set by the copy.
+func (w *stepWriter) writeStatus() gin.ResponseWriter {
+	rw := w.response()
+	if rw != nil && !rw.Written() && w.ResponseWriter.Status() != w.view.status {
+		rw.WriteHeader(w.ResponseWriter.Status())
+	}
+	return rw
+}
+
+func (w *stepWriter) Header() http.Header {
+	w.view.run.mu.Lock()
+	defer w.view.run.mu.Unlock()
+	if rw := w.response(); rw != nil {
+		return rw.Header()
+	}
+	if w.header == nil {
+		w.header = make(http.Header)
+	}
+	return w.header
+}
+
+func (w *stepWriter) WriteHeaderNow() {
+	w.view.run.mu.Lock()
+	defer w.view.run.mu.Unlock()
+	if rw := w.writeStatus(); rw != nil {
+		rw.WriteHeaderNow()
+	}
+}
+
+func (w *stepWriter) Write(data []byte) (int, error) {
+	w.view.run.mu.Lock()
+	defer w.view.run.mu.Unlock()
+	if rw := w.writeStatus(); rw != nil {
+		return rw.Write(data)
+	}
+	return len(data), nil
+}
+
+func (w *stepWriter) WriteString(s string) (int, error) {
+	w.view.run.mu.Lock()
+	defer w.view.run.mu.Unlock()
+	if rw := w.writeStatus(); rw != nil {
+		return rw.WriteString(s)
+	}
+	return len(s), nil
+}
+
+func (w *stepWriter) Written() bool {
+	w.view.run.mu.Lock()
+	defer w.view.run.mu.Unlock()
+	if rw := w.response(); rw != nil {
+		return rw.Written()
+	}
+	return w.ResponseWriter.Written()
+}
+
+func (w *stepWriter) Size() int {
+	w.view.run.mu.Lock()
+	defer w.view.run.mu.Unlock()
+	if rw := w.response(); rw != nil {
+		return rw.Size()
+	}
+	return w.ResponseWriter.Size()
+}
+
+func (w *stepWriter) Flush() {
+	w.view.run.mu.Lock()
+	defer w.view.run.mu.Unlock()
+	if rw := w.writeStatus(); rw != nil {
+		rw.Flush()
+	}
+}
+
+func (w *stepWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	w.view.run.mu.Lock()
+	defer w.view.run.mu.Unlock()
+	if rw := w.response(); rw != nil {
+		return rw.Hijack()
+	}
+	return nil, nil, errStepLeft
+}
+
+func (w *stepWriter) CloseNotify() <-chan bool {
+	w.view.run.mu.Lock()
+	defer w.view.run.mu.Unlock()
+	if rw := w.response(); rw != nil {
+		return rw.CloseNotify()
+	}
+	return make(chan bool)
+}
+
+func (w *stepWriter) Pusher() http.Pusher {
+	w.view.run.mu.Lock()
+	defer w.view.run.mu.Unlock()
+	if rw := w.response(); rw != nil {
+		return rw.Pusher()
+	}
+	return nil
+}
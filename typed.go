@@ -0,0 +1,113 @@
+// MIT License
+
+// Copyright (c) 2019 tanzy2018
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package groute
+
+import (
+	"reflect"
+	"time"
+)
+
+// TypedHandleFunc - handle function receiving the bound param and returning the response.
+type TypedHandleFunc[P any, R any] func(c *Context, param *P) (R, error)
+
+// TypedInterface define the router interface with compile-time Param and response types,
+// P must be a struct,or the interface is rejected by the router.
+type TypedInterface[P any, R any] struct {
+	// SyncHandleFunc - same as Interface.SyncHandleFunc.
+	SyncHandleFunc ErrHandleFuncChain
+	// AsyncHandleFunc - same as Interface.AsyncHandleFunc.
+	AsyncHandleFunc ErrHandleFuncChain
+	// Steps - same as Interface.Steps.
+	Steps []Step
+	// StepTimeout - same as Interface.StepTimeout.
+	StepTimeout time.Duration
+	// StepBudget - same as Interface.StepBudget.
+	StepBudget time.Duration
+	// StepFailure - same as Interface.StepFailure.
+	StepFailure StepFailure
+	// StepConcurrency - same as Interface.StepConcurrency.
+	StepConcurrency int
+	// StepSaturation - same as Interface.StepSaturation.
+	StepSaturation Saturation
+	// Path - starts with "/".
+	Path string
+	// Method - same as Interface.Method.
+	Method string
+	// Methods - same as Interface.Methods.
+	Methods []string
+	// Handle function that handles the business logic,
+	// the returned error is passed to ErrHandle.
+	Handle TypedHandleFunc[P, R]
+	// ErrHandle
+	ErrHandle ErrHandle
+}
+
+// interfacer is implemented by every typed interface which can be served as Interface.
+type interfacer interface {
+	Interface() Interface
+}
+
+var interfacerType = reflect.TypeOf((*interfacer)(nil)).Elem()
+
+// NewTypedInterface - create a new TypedInterface instance.
+func NewTypedInterface[P any, R any](inter TypedInterface[P, R], handle func(c *Context, param *P) (R, error)) TypedInterface[P, R] {
+	inter.Handle = handle
+	return inter
+}
+
+// Interface - convert to the Interface registered by the router,
+// the response and the error are written by the ResponseEncoder of the router.
+func (ti TypedInterface[P, R]) Interface() Interface {
+	var param P
+	inter := Interface{
+		SyncHandleFunc:  ti.SyncHandleFunc,
+		AsyncHandleFunc: ti.AsyncHandleFunc,
+		Steps:           ti.Steps,
+		StepTimeout:     ti.StepTimeout,
+		StepBudget:      ti.StepBudget,
+		StepFailure:     ti.StepFailure,
+		StepConcurrency: ti.StepConcurrency,
+		StepSaturation:  ti.StepSaturation,
+		Path:            ti.Path,
+		Method:          ti.Method,
+		Param:           param,
+		ErrHandle:       ti.ErrHandle,
+		response:        reflect.TypeOf((*R)(nil)).Elem(),
+	}
+	// no Handle,rejected by the router.
+	if ti.Handle == nil {
+		return inter
+	}
+	inter.ResultHandle = func(c *Context) (interface{}, error) {
+		p, _ := c.Param.(*P)
+		if p == nil {
+			p = new(P)
+		}
+		rsp, err := ti.Handle(c, p)
+		if err != nil {
+			return nil, err
+		}
+		return rsp, nil
+	}
+	return inter
+}
@@ -0,0 +1,167 @@
+// MIT License
+
+// Copyright (c) 2019 tanzy2018
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package groute
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	ut "github.com/go-playground/universal-translator"
+	validatorv10 "github.com/go-playground/validator/v10"
+	validatorv8 "gopkg.in/go-playground/validator.v8"
+	validator "gopkg.in/go-playground/validator.v9"
+)
+
+// customValidator - validator which can register the custom rules.
+type customValidator interface {
+	registerValidation(tag string, fn interface{}, translations map[string]string) error
+	registerStructValidation(typ interface{}, fn interface{}) error
+}
+
+// RegisterValidation - register the custom rule to the validator of the router,
+// fn is the validation Func of the validator version,e.g. validator.Func of v9.
+// translations are keyed by the locale,e.g. {"en": "{0} must be a valid sku"},
+// where {0} is the field and {1} the param of the rule;
+// the locales without translation fall back to the default locale,then en.
+func (r *Router) RegisterValidation(tag string, fn interface{}, translations map[string]string) error {
+	v, ok := r.structValidator.(customValidator)
+	if !ok {
+		return fmt.Errorf("the validator [realType:%T] can't register validation", r.structValidator)
+	}
+	return v.registerValidation(tag, fn, translations)
+}
+
+// RegisterStructValidation - register the struct level validation of the type,
+// fn is the StructLevelFunc of the validator version.
+func (r *Router) RegisterStructValidation(typ interface{}, fn interface{}) error {
+	v, ok := r.structValidator.(customValidator)
+	if !ok {
+		return fmt.Errorf("the validator [realType:%T] can't register validation", r.structValidator)
+	}
+	return v.registerStructValidation(typ, fn)
+}
+
+// convertFunc - convert fn to the func type,so both the named type and the literal are accepted.
+func convertFunc(fn interface{}, typ reflect.Type) (interface{}, bool) {
+	v := reflect.ValueOf(fn)
+	if !v.IsValid() || v.Kind() != reflect.Func || v.IsNil() || !v.Type().ConvertibleTo(typ) {
+		return nil, false
+	}
+	return v.Convert(typ).Interface(), true
+}
+
+// translationOf - translation of the locale,fall back to the default locale,then en.
+func translationOf(translations map[string]string, locale string, defaultLocale string) string {
+	for _, l := range []string{locale, defaultLocale, "en"} {
+		if text, ok := translations[l]; ok {
+			return text
+		}
+	}
+	return ""
+}
+
+// fieldError - validation error of one field,independent of the validator version.
+type fieldError struct {
+	// namespace - path of the field by the struct field names below the validated struct,
+	// e.g. Items[2].Sku.
+	namespace string
+	// tag - the failed rule,or its alias.
+	tag   string
+	value interface{}
+	// message - the translated hint,empty if the validator can't translate.
+	message string
+}
+
+// fieldErrors - convert the validation errors of any validator version,
+// reports false if err is not the validation errors.
+func fieldErrors(err error, trans ut.Translator) ([]fieldError, bool) {
+	var fes []fieldError
+	switch errs := err.(type) {
+	case validatorv8.ValidationErrors:
+		for _, e := range errs {
+			fes = append(fes, fieldError{namespace: trimNamespace(e.FieldNamespace), tag: e.Tag, value: e.Value})
+		}
+	case validator.ValidationErrors:
+		for _, e := range errs {
+			fe := fieldError{namespace: trimNamespace(e.StructNamespace()), tag: e.Tag(), value: e.Value()}
+			if trans != nil {
+				fe.message = e.Translate(trans)
+			}
+			fes = append(fes, fe)
+		}
+	case validatorv10.ValidationErrors:
+		for _, e := range errs {
+			fe := fieldError{namespace: trimNamespace(e.StructNamespace()), tag: e.Tag(), value: e.Value()}
+			if trans != nil {
+				fe.message = e.Translate(trans)
+			}
+			fes = append(fes, fe)
+		}
+	default:
+		return nil, false
+	}
+	return fes, true
+}
+
+// trimNamespace - trim the name of the validated struct from the namespace.
+func trimNamespace(ns string) string {
+	if i := strings.IndexByte(ns, '.'); i >= 0 {
+		return ns[i+1:]
+	}
+	return ns
+}
+
+// nsSegment - one level of the namespace,e.g. Items[2].
+type nsSegment struct {
+	name string
+	// index - the indexes of the slice,array or map,e.g. [2] or [k][0].
+	index   string
+	indexes int
+}
+
+// splitNamespace - split the namespace into levels,the map keys may contain '.'.
+func splitNamespace(ns string) []nsSegment {
+	var (
+		segs []nsSegment
+		seg  nsSegment
+	)
+	for i := 0; i < len(ns); i++ {
+		switch ns[i] {
+		case '.':
+			segs = append(segs, seg)
+			seg = nsSegment{}
+		case '[':
+			j := strings.IndexByte(ns[i:], ']')
+			if j < 0 {
+				j = len(ns) - 1 - i
+			}
+			seg.index += ns[i : i+j+1]
+			seg.indexes++
+			i += j
+		default:
+			seg.name += ns[i : i+1]
+		}
+	}
+	return append(segs, seg)
+}